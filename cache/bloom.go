@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/encrypt"
+)
+
+// redis 单个 bitmap 最大 512MB，即 2^32 位
+const _MaxBloomBits = uint64(1) << 32
+
+// BloomFilter 基于 Redis bitmap 的布隆过滤器
+// 用于在请求到达 MySQL 之前拦截不存在的商品ID、重复的购买请求等。
+// MayContain 返回 false 时元素一定不存在；返回 true 时元素可能存在（存在误判）。
+type BloomFilter struct {
+	repo Repo
+	key  string
+
+	bits   uint64 // bitmap 位数 m
+	hashes uint64 // 哈希函数个数 k
+}
+
+// NewBloomFilter 根据预期元素个数 expectedItems 和误判率 fpRate 计算 bitmap 大小与哈希函数个数
+// fpRate 取值范围 (0, 1)
+func NewBloomFilter(repo Repo, key string, expectedItems uint64, fpRate float64) (*BloomFilter, error) {
+	if expectedItems == 0 {
+		return nil, fmt.Errorf("bloom filter expectedItems must be greater than 0 ")
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("bloom filter fpRate must be in (0, 1) ")
+	}
+
+	bits, hashes := bloomEstimate(expectedItems, fpRate)
+	if bits > _MaxBloomBits {
+		return nil, fmt.Errorf("bloom filter need %d bits, exceeds redis bitmap limit ", bits)
+	}
+
+	return &BloomFilter{
+		repo:   repo,
+		key:    key,
+		bits:   bits,
+		hashes: hashes,
+	}, nil
+}
+
+// bloomEstimate 计算最优的 m 和 k
+// m = -n*ln(p) / (ln2)^2
+// k = m/n * ln2
+func bloomEstimate(n uint64, p float64) (m uint64, k uint64) {
+	m = uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k = uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k == 0 {
+		k = 1
+	}
+	return m, k
+}
+
+// Bits bitmap 位数
+func (b *BloomFilter) Bits() uint64 {
+	return b.bits
+}
+
+// Hashes 哈希函数个数
+func (b *BloomFilter) Hashes() uint64 {
+	return b.hashes
+}
+
+// Add 添加元素
+func (b *BloomFilter) Add(ctx context.Context, item string) error {
+	offsets := b.offsets(item)
+
+	_, err := b.repo.Client().Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, offset := range offsets {
+			pipe.SetBit(ctx, b.key, int64(offset), 1)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis bloom add key: %s err %w", b.key, err)
+	}
+	return nil
+}
+
+// MayContain 判断元素是否可能存在
+func (b *BloomFilter) MayContain(ctx context.Context, item string) (bool, error) {
+	offsets := b.offsets(item)
+
+	cmds := make([]*redis.IntCmd, len(offsets))
+	_, err := b.repo.Client().Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, offset := range offsets {
+			cmds[i] = pipe.GetBit(ctx, b.key, int64(offset))
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("redis bloom check key: %s err %w", b.key, err)
+	}
+
+	for _, cmd := range cmds {
+		if cmd.Val() == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// offsets 双重哈希生成 k 个位置
+// g_i(x) = h1(x) + i*h2(x) mod m
+// h1 取自 MD5，h2 取自 SHA256
+func (b *BloomFilter) offsets(item string) []uint64 {
+	h1, _ := strconv.ParseUint(encrypt.MD5(item)[:16], 16, 64)
+	h2, _ := strconv.ParseUint(encrypt.SHA256(item)[:16], 16, 64)
+
+	// h2 mod m 为 0 时 k 个位置会重合为一个
+	h1 %= b.bits
+	h2 %= b.bits
+	if h2 == 0 {
+		h2 = 1
+	}
+
+	offsets := make([]uint64, b.hashes)
+	for i := uint64(0); i < b.hashes; i++ {
+		offsets[i] = (h1 + i*h2) % b.bits
+	}
+	return offsets
+}
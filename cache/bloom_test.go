@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomEstimate(t *testing.T) {
+	m, k := bloomEstimate(1000000, 0.01)
+	assert.Equal(t, uint64(9585059), m)
+	assert.Equal(t, uint64(7), k)
+
+	m, k = bloomEstimate(1, 0.9)
+	assert.Equal(t, uint64(1), m)
+	assert.Equal(t, uint64(1), k)
+}
+
+func TestNewBloomFilter(t *testing.T) {
+	_, err := NewBloomFilter(nil, "bloom", 0, 0.01)
+	assert.Error(t, err)
+
+	_, err = NewBloomFilter(nil, "bloom", 100, 1)
+	assert.Error(t, err)
+
+	b, err := NewBloomFilter(nil, "bloom", 100000, 0.001)
+	assert.NoError(t, err)
+
+	offsets := b.offsets("product_1")
+	assert.Len(t, offsets, int(b.Hashes()))
+	for _, offset := range offsets {
+		assert.Less(t, offset, b.Bits())
+	}
+	// 相同元素的位置必须稳定
+	assert.Equal(t, offsets, b.offsets("product_1"))
+}
+
+func TestBloomFilter(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	bloom, err := NewBloomFilter(repo, "sk:bloom:product", 1000, 0.01)
+	assert.NoError(t, err)
+
+	assert.NoError(t, bloom.Add(ctx, "product_1"))
+	ok, err := bloom.MayContain(ctx, "product_1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = bloom.MayContain(ctx, "product_2")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// 加满预期元素后误判率应接近 fpRate
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, bloom.Add(ctx, fmt.Sprintf("product_%d", i)))
+	}
+	falsePositive := 0
+	for i := 1000; i < 3000; i++ {
+		ok, err = bloom.MayContain(ctx, fmt.Sprintf("product_%d", i))
+		assert.NoError(t, err)
+		if ok {
+			falsePositive++
+		}
+	}
+	assert.Less(t, falsePositive, 60)
+}
+
+func TestBloomFilter_Offsets(t *testing.T) {
+	// bits 很小时 h2 mod m 容易为 0，k 个位置不能重合
+	b := &BloomFilter{bits: 2, hashes: 2}
+	for i := 0; i < 100; i++ {
+		offsets := b.offsets(fmt.Sprintf("product_%d", i))
+		assert.NotEqual(t, offsets[0], offsets[1])
+	}
+}
+
+func TestBitmap(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	day := time.Date(2022, 6, 18, 10, 0, 0, 0, time.Local)
+
+	bitmap := NewBitmap(repo, "sk:joined", 48*time.Hour)
+	assert.Equal(t, "sk:joined:20220618", bitmap.Key(day))
+
+	existed, err := bitmap.SetBit(ctx, day, 10086, true)
+	assert.NoError(t, err)
+	assert.False(t, existed)
+
+	// 重复参与
+	existed, err = bitmap.SetBit(ctx, day, 10086, true)
+	assert.NoError(t, err)
+	assert.True(t, existed)
+
+	_, err = bitmap.SetBit(ctx, day, 1, true)
+	assert.NoError(t, err)
+
+	ok, err := bitmap.GetBit(ctx, day, 2)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	count, err := bitmap.Count(ctx, day)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	ttl, err := repo.TTL(ctx, bitmap.Key(day))
+	assert.NoError(t, err)
+	assert.True(t, ttl > 0)
+}
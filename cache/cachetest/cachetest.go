@@ -0,0 +1,30 @@
+package cachetest
+
+// package cachetest 测试辅助
+// 启动一个内嵌的 Redis 协议服务（miniredis），无需本地 Redis 即可测试依赖 cache.Repo 的代码。
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/HYY-yu/seckill.pkg/cache"
+)
+
+// NewRepo 启动内嵌 Redis 服务并返回连接它的 cache.Repo
+// 测试结束时自动关闭。返回的 *miniredis.Miniredis 可用于 FastForward 模拟时间流逝。
+func NewRepo(tb testing.TB, options ...cache.Option) (cache.Repo, *miniredis.Miniredis) {
+	tb.Helper()
+
+	server := miniredis.RunT(tb)
+	repo, err := cache.New("cachetest", &cache.RedisConf{
+		Addr: server.Addr(),
+	}, options...)
+	if err != nil {
+		tb.Fatalf("cachetest: connect embedded redis error %v", err)
+	}
+	tb.Cleanup(func() {
+		_ = repo.Close()
+	})
+	return repo, server
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const _KeySeparator = ":"
+
+// KeySchema key 命名空间
+// 同一个 Redis 被多个服务共用时，以 prefix（默认为 serverName）区分，避免 key 冲突。
+//
+//	schema := cache.NewKeySchema("order")
+//	stockKey := schema.Template("stock", "product_id")
+//	stockKey.Build(1001)                // order:stock:v1:1001
+//	stockKey.WithVersion(2).Build(1001) // order:stock:v2:1001
+type KeySchema struct {
+	prefix string
+}
+
+// NewKeySchema 新建 KeySchema
+func NewKeySchema(prefix string) *KeySchema {
+	return &KeySchema{
+		prefix: prefix,
+	}
+}
+
+// Prefix 命名空间前缀
+func (s *KeySchema) Prefix() string {
+	return s.prefix
+}
+
+// Template 声明 key 模板，fields 为 key 中的变量名，Build 时需按顺序传入相同个数的参数
+func (s *KeySchema) Template(name string, fields ...string) *KeyTemplate {
+	return &KeyTemplate{
+		prefix:  s.prefix,
+		name:    name,
+		fields:  fields,
+		version: 1,
+	}
+}
+
+// KeyTemplate key 模板
+// 格式为 prefix:name:v<version>:field1:field2...，版本号默认为 1
+type KeyTemplate struct {
+	prefix  string
+	name    string
+	fields  []string
+	version int
+}
+
+// WithVersion 返回指定版本的模板
+// 缓存结构变更时提升版本号，旧版本的 key 不再被读取，再通过 DelByPattern(OldTemplate.Pattern()) 批量清理。
+func (t *KeyTemplate) WithVersion(version int) *KeyTemplate {
+	if version < 1 {
+		panic(fmt.Sprintf("cache key %s version must be greater than 0", t.name))
+	}
+	nt := *t
+	nt.version = version
+	return &nt
+}
+
+// Version 当前版本号
+func (t *KeyTemplate) Version() int {
+	return t.version
+}
+
+// Build 生成 key，args 个数必须与声明的 fields 一致，否则 panic
+func (t *KeyTemplate) Build(args ...interface{}) string {
+	if len(args) != len(t.fields) {
+		panic(fmt.Sprintf("cache key %s need %d args %v, got %d", t.name, len(t.fields), t.fields, len(args)))
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(t.base())
+	for _, arg := range args {
+		sb.WriteString(_KeySeparator)
+		sb.WriteString(fmt.Sprint(arg))
+	}
+	return sb.String()
+}
+
+// Pattern 匹配当前版本下所有 key 的 SCAN pattern
+func (t *KeyTemplate) Pattern() string {
+	pattern := escapePattern(t.base())
+	if len(t.fields) > 0 {
+		pattern += _KeySeparator + "*"
+	}
+	return pattern
+}
+
+func (t *KeyTemplate) base() string {
+	parts := make([]string, 0, 3)
+	if t.prefix != "" {
+		parts = append(parts, t.prefix)
+	}
+	parts = append(parts, t.name, "v"+strconv.Itoa(t.version))
+	return strings.Join(parts, _KeySeparator)
+}
+
+// escapePattern 转义 glob 特殊字符
+func escapePattern(s string) string {
+	sb := strings.Builder{}
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyTemplate(t *testing.T) {
+	schema := NewKeySchema("sk")
+
+	t.Run("Build", func(t *testing.T) {
+		refresh := schema.Template("refresh", "token")
+		assert.Equal(t, "sk:refresh:v1:abc", refresh.Build("abc"))
+
+		order := schema.Template("order", "user_id", "product_id")
+		assert.Equal(t, "sk:order:v1:1:1001", order.Build(1, int64(1001)))
+		assert.Panics(t, func() {
+			order.Build(1)
+		})
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		stock := schema.Template("stock", "product_id")
+		stockV2 := stock.WithVersion(2)
+
+		assert.Equal(t, "sk:stock:v1:1001", stock.Build(1001))
+		assert.Equal(t, "sk:stock:v2:1001", stockV2.Build(1001))
+		assert.Equal(t, 1, stock.Version())
+		assert.Panics(t, func() {
+			stock.WithVersion(0)
+		})
+	})
+
+	t.Run("Pattern", func(t *testing.T) {
+		assert.Equal(t, "sk:stock:v1:*", schema.Template("stock", "product_id").Pattern())
+		assert.Equal(t, "sk:stock:v3:*", schema.Template("stock", "product_id").WithVersion(3).Pattern())
+		assert.Equal(t, "sk:config:v1", schema.Template("config").Pattern())
+		assert.Equal(t, `a\*:b\?:v1:*`, NewKeySchema("a*").Template("b?", "id").Pattern())
+	})
+}
@@ -0,0 +1,255 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var _ Repo = (*memoryRepo)(nil)
+
+// memoryRepo 纯内存实现的 Repo，用于单元测试
+// 支持 TTL 语义，key 不存在时 Get 返回的错误同样可以用 errors.Is(err, redis.Nil) 判断。
+// 不依赖 Redis 连接，所以 Client() 返回 nil，依赖 Client() 的功能（如 BloomFilter、stream）
+// 请使用 cachetest.NewRepo 启动内嵌的 Redis 协议服务。
+type memoryRepo struct {
+	mu        sync.Mutex
+	items     map[string]*memoryItem
+	keySchema *KeySchema
+}
+
+type memoryItem struct {
+	value    string
+	expireAt time.Time // 零值表示不过期
+}
+
+func (i *memoryItem) expired(now time.Time) bool {
+	return !i.expireAt.IsZero() && !now.Before(i.expireAt)
+}
+
+// NewMemoryRepo 新建内存 Repo，keyPrefix 为可选的 key 命名空间前缀
+func NewMemoryRepo(keyPrefix ...string) Repo {
+	prefix := ""
+	if len(keyPrefix) > 0 {
+		prefix = keyPrefix[0]
+	}
+
+	return &memoryRepo{
+		items:     make(map[string]*memoryItem),
+		keySchema: NewKeySchema(prefix),
+	}
+}
+
+// get 获取未过期的 item，已过期的顺便删除，调用方需持有锁
+func (m *memoryRepo) get(key string) (*memoryItem, bool) {
+	item, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	if item.expired(time.Now()) {
+		delete(m.items, key)
+		return nil, false
+	}
+	return item, true
+}
+
+func (m *memoryRepo) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item := &memoryItem{value: value}
+	if ttl > 0 {
+		item.expireAt = time.Now().Add(ttl)
+	}
+	m.items[key] = item
+	return nil
+}
+
+func (m *memoryRepo) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.get(key); ok {
+		return false, nil
+	}
+	item := &memoryItem{value: value}
+	if ttl > 0 {
+		item.expireAt = time.Now().Add(ttl)
+	}
+	m.items[key] = item
+	return true, nil
+}
+
+func (m *memoryRepo) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.get(key)
+	if !ok {
+		return "", fmt.Errorf("redis get key: %s err %w", key, redis.Nil)
+	}
+	return item.value, nil
+}
+
+// TTL 与 go-redis 保持一致：key 不存在返回 -2，没有过期时间返回 -1
+func (m *memoryRepo) TTL(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.get(key)
+	if !ok {
+		return -2, nil
+	}
+	if item.expireAt.IsZero() {
+		return -1, nil
+	}
+	return time.Until(item.expireAt), nil
+}
+
+func (m *memoryRepo) Expire(ctx context.Context, key string, ttl time.Duration) bool {
+	return m.ExpireAt(ctx, key, time.Now().Add(ttl))
+}
+
+func (m *memoryRepo) ExpireAt(ctx context.Context, key string, ttl time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.get(key)
+	if !ok {
+		return false
+	}
+	item.expireAt = ttl
+	return true
+}
+
+func (m *memoryRepo) Del(ctx context.Context, key string) bool {
+	if key == "" {
+		return true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.get(key)
+	delete(m.items, key)
+	return ok
+}
+
+func (m *memoryRepo) Exists(ctx context.Context, keys ...string) bool {
+	if len(keys) == 0 {
+		return true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if _, ok := m.get(key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryRepo) Incr(ctx context.Context, key string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.get(key)
+	if !ok {
+		item = &memoryItem{value: "0"}
+		m.items[key] = item
+	}
+
+	value, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	value++
+	item.value = strconv.FormatInt(value, 10)
+	return value
+}
+
+func (m *memoryRepo) DelByPattern(ctx context.Context, pattern string) (int64, error) {
+	if pattern == "" || pattern == "*" {
+		return 0, fmt.Errorf("redis del by pattern: %q is not allowed ", pattern)
+	}
+
+	re, err := globToRegexp(pattern)
+	if err != nil {
+		return 0, fmt.Errorf("redis del by pattern: %s err %w", pattern, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	now := time.Now()
+	for key, item := range m.items {
+		if !re.MatchString(key) {
+			continue
+		}
+		delete(m.items, key)
+		if !item.expired(now) {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *memoryRepo) KeySchema() *KeySchema {
+	return m.keySchema
+}
+
+// Client 内存实现没有 Redis 连接
+func (m *memoryRepo) Client() *redis.Client {
+	return nil
+}
+
+func (m *memoryRepo) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *memoryRepo) Close() error {
+	return nil
+}
+
+// globToRegexp 将 Redis 的 glob pattern 转换为正则
+// 支持 * ? [abc] [^a] [a-z] 以及 \ 转义
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	sb := strings.Builder{}
+	sb.WriteByte('^')
+
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+			sb.WriteByte(c)
+		case c == '*':
+			sb.WriteString(".*")
+		case c == '?':
+			sb.WriteByte('.')
+		case c == '[':
+			inClass = true
+			sb.WriteByte(c)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteByte('$')
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRepo(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo("sk")
+
+	t.Run("SetAndGet", func(t *testing.T) {
+		assert.NoError(t, repo.Set(ctx, "k1", "v1", 0))
+		v, err := repo.Get(ctx, "k1")
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", v)
+
+		_, err = repo.Get(ctx, "not_exists")
+		assert.True(t, errors.Is(err, redis.Nil))
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		assert.NoError(t, repo.Set(ctx, "k2", "v2", 50*time.Millisecond))
+		ttl, err := repo.TTL(ctx, "k2")
+		assert.NoError(t, err)
+		assert.True(t, ttl > 0 && ttl <= 50*time.Millisecond)
+		assert.True(t, repo.Exists(ctx, "k2"))
+
+		time.Sleep(60 * time.Millisecond)
+		assert.False(t, repo.Exists(ctx, "k2"))
+		ttl, _ = repo.TTL(ctx, "k2")
+		assert.Equal(t, time.Duration(-2), ttl)
+
+		ttl, _ = repo.TTL(ctx, "k1")
+		assert.Equal(t, time.Duration(-1), ttl)
+
+		assert.True(t, repo.Expire(ctx, "k1", time.Hour))
+		assert.False(t, repo.Expire(ctx, "k2", time.Hour))
+		assert.True(t, repo.ExpireAt(ctx, "k1", time.Now().Add(-time.Second)))
+		assert.False(t, repo.Exists(ctx, "k1"))
+	})
+
+	t.Run("SetNX", func(t *testing.T) {
+		ok, err := repo.SetNX(ctx, "lock", "a", 50*time.Millisecond)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		ok, _ = repo.SetNX(ctx, "lock", "b", 50*time.Millisecond)
+		assert.False(t, ok)
+		v, _ := repo.Get(ctx, "lock")
+		assert.Equal(t, "a", v)
+
+		time.Sleep(60 * time.Millisecond)
+		ok, _ = repo.SetNX(ctx, "lock", "b", 0)
+		assert.True(t, ok)
+	})
+
+	t.Run("IncrAndDel", func(t *testing.T) {
+		assert.Equal(t, int64(1), repo.Incr(ctx, "counter"))
+		assert.Equal(t, int64(2), repo.Incr(ctx, "counter"))
+		assert.True(t, repo.Del(ctx, "counter"))
+		assert.False(t, repo.Del(ctx, "counter"))
+	})
+
+	t.Run("DelByPattern", func(t *testing.T) {
+		stock := repo.KeySchema().Template("stock", "product_id")
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, repo.Set(ctx, stock.Build(i), "1", 0))
+		}
+		assert.NoError(t, repo.Set(ctx, "sk:stock_log", "1", 0))
+
+		n, err := repo.DelByPattern(ctx, stock.Pattern())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), n)
+		assert.True(t, repo.Exists(ctx, "sk:stock_log"))
+
+		_, err = repo.DelByPattern(ctx, "*")
+		assert.Error(t, err)
+	})
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	cases := []struct {
+		pattern string
+		key     string
+		match   bool
+	}{
+		{"sk:*", "sk:a:b", true},
+		{"sk:?", "sk:a", true},
+		{"sk:?", "sk:ab", false},
+		{"sk:[ab]", "sk:b", true},
+		{"sk:[^ab]", "sk:b", false},
+		{`sk\*`, "sk*", true},
+		{`sk\*`, "skx", false},
+		{"sk.a", "skxa", false},
+	}
+	for _, c := range cases {
+		re, err := globToRegexp(c.pattern)
+		assert.NoError(t, err)
+		assert.Equal(t, c.match, re.MatchString(c.key), c.pattern+" "+c.key)
+	}
+}
@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/prometheus_helper"
+)
+
+// 命令级别的 prometheus metrics
+// 通过 go-redis Hook 记录每个命令的耗时与错误，并对慢命令输出日志
+
+type startTimeKey struct{}
+
+type commandMetrics struct {
+	duration  *prometheus.HistogramVec
+	errors    *prometheus.CounterVec
+	getResult *prometheus.CounterVec
+
+	logger        *zap.Logger
+	slowThreshold time.Duration
+}
+
+var _ redis.Hook = (*commandMetrics)(nil)
+
+func newCommandMetrics(systemName string, logger *zap.Logger, slowThreshold time.Duration) *commandMetrics {
+	labels := prometheus.Labels{
+		"system_name": systemName,
+	}
+
+	m := &commandMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "go_redis_command_duration_seconds",
+			Help:        "Redis command latency in seconds.",
+			ConstLabels: labels,
+			Buckets:     []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"command"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "go_redis_command_errors_total",
+			Help:        "Number of redis command errors, redis.Nil is not counted.",
+			ConstLabels: labels,
+		}, []string{"command"}),
+		getResult: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "go_redis_get_total",
+			Help:        "Number of Repo.Get calls, partitioned by hit or miss.",
+			ConstLabels: labels,
+		}, []string{"result"}),
+		logger:        logger,
+		slowThreshold: slowThreshold,
+	}
+
+	// 相同 systemName 多次创建 Repo 时，复用已注册的指标
+	m.duration = prometheus_helper.RegisterOrExisting(m.duration).(*prometheus.HistogramVec)
+	m.errors = prometheus_helper.RegisterOrExisting(m.errors).(*prometheus.CounterVec)
+	m.getResult = prometheus_helper.RegisterOrExisting(m.getResult).(*prometheus.CounterVec)
+	return m
+}
+
+// BeforeProcess implements redis.Hook.
+func (m *commandMetrics) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startTimeKey{}, time.Now()), nil
+}
+
+// AfterProcess implements redis.Hook.
+func (m *commandMetrics) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	start, ok := ctx.Value(startTimeKey{}).(time.Time)
+	if !ok {
+		return nil
+	}
+	cost := time.Since(start)
+
+	m.duration.WithLabelValues(cmd.Name()).Observe(cost.Seconds())
+	m.recordError(cmd)
+	m.logSlow(cmd.Name(), cost, cmd)
+	return nil
+}
+
+// BeforeProcessPipeline implements redis.Hook.
+func (m *commandMetrics) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startTimeKey{}, time.Now()), nil
+}
+
+// AfterProcessPipeline implements redis.Hook.
+// pipeline 整体记录一次耗时，错误按命令分别记录
+func (m *commandMetrics) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	start, ok := ctx.Value(startTimeKey{}).(time.Time)
+	if !ok {
+		return nil
+	}
+	cost := time.Since(start)
+
+	m.duration.WithLabelValues("pipeline").Observe(cost.Seconds())
+	for _, cmd := range cmds {
+		m.recordError(cmd)
+	}
+	m.logSlow("pipeline", cost, cmds...)
+	return nil
+}
+
+func (m *commandMetrics) recordError(cmd redis.Cmder) {
+	if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+		m.errors.WithLabelValues(cmd.Name()).Inc()
+	}
+}
+
+func (m *commandMetrics) logSlow(name string, cost time.Duration, cmds ...redis.Cmder) {
+	if m.logger == nil || m.slowThreshold <= 0 || cost < m.slowThreshold {
+		return
+	}
+
+	// 只记录命令名与 key，避免 value 过大
+	keys := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		if args := cmd.Args(); len(args) > 1 {
+			keys = append(keys, fmt.Sprintf("%s %v", cmd.Name(), args[1]))
+		} else {
+			keys = append(keys, cmd.Name())
+		}
+	}
+
+	m.logger.Warn("redis slow command",
+		zap.String("command", name),
+		zap.Strings("keys", keys),
+		zap.Duration("cost", cost),
+		zap.Duration("threshold", m.slowThreshold),
+	)
+}
+
+// recordGet 记录 Get 的命中情况
+func (m *commandMetrics) recordGet(err error) {
+	switch {
+	case err == nil:
+		m.getResult.WithLabelValues("hit").Inc()
+	case errors.Is(err, redis.Nil):
+		m.getResult.WithLabelValues("miss").Inc()
+	}
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestCommandMetrics(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	m := newCommandMetrics("hook_test", zap.New(core), time.Millisecond)
+	ctx := context.Background()
+
+	process := func(cmd redis.Cmder, cost time.Duration) {
+		hookCtx, err := m.BeforeProcess(ctx, cmd)
+		assert.NoError(t, err)
+		time.Sleep(cost)
+		assert.NoError(t, m.AfterProcess(hookCtx, cmd))
+	}
+
+	hit := redis.NewStringCmd(ctx, "get", "k1")
+	process(hit, 0)
+	m.recordGet(hit.Err())
+
+	miss := redis.NewStringCmd(ctx, "get", "k2")
+	miss.SetErr(redis.Nil)
+	process(miss, 0)
+	m.recordGet(miss.Err())
+
+	failed := redis.NewStatusCmd(ctx, "set", "k3", "v")
+	failed.SetErr(errors.New("READONLY"))
+	process(failed, 2*time.Millisecond)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(m.duration))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.errors.WithLabelValues("get")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.errors.WithLabelValues("set")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.getResult.WithLabelValues("hit")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.getResult.WithLabelValues("miss")))
+
+	slow := logs.FilterMessage("redis slow command").All()
+	if assert.NotEmpty(t, slow) {
+		assert.Equal(t, "set", slow[len(slow)-1].ContextMap()["command"])
+	}
+
+	// 同名 system 再次创建时复用已注册的指标
+	again := newCommandMetrics("hook_test", nil, 0)
+	assert.Equal(t, m.errors, again.errors)
+}
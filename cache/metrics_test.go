@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v8"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -18,10 +19,10 @@ import (
 func TestRegister(t *testing.T) {
 	ctx := context.Background()
 
+	server := miniredis.RunT(t)
 	rc := redis.NewClient(&redis.Options{
-		Addr:        "localhost:6379",
+		Addr:        server.Addr(),
 		DialTimeout: time.Second,
-		Password:    "redis",
 	})
 	defer func(rc *redis.Client) {
 		_ = rc.Close()
@@ -30,7 +31,7 @@ func TestRegister(t *testing.T) {
 	if err := rc.Ping(ctx).Err(); err != nil {
 		t.Fatal(err)
 	}
-	c := NewPoolStatsCollector(rc, "test")
+	c := NewPoolStatsCollector(rc, "pool_stats_test")
 	if err := prometheus.Register(c); err != nil {
 		t.Fatal(err)
 	}
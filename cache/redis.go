@@ -10,10 +10,16 @@ import (
 	"github.com/go-redis/redis/v8"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 )
 
+// SCAN 每批次数量
+const _ScanCount = 500
+
 type Repo interface {
 	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetNX key 不存在时设置，返回是否设置成功，可用作分布式锁、去重
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
 	Get(ctx context.Context, key string) (string, error)
 	TTL(ctx context.Context, key string) (time.Duration, error)
 	Expire(ctx context.Context, key string, ttl time.Duration) bool
@@ -21,13 +27,21 @@ type Repo interface {
 	Del(ctx context.Context, key string) bool
 	Exists(ctx context.Context, keys ...string) bool
 	Incr(ctx context.Context, key string) int64
+	// DelByPattern 使用 SCAN 删除匹配 pattern 的 key，返回删除个数
+	DelByPattern(ctx context.Context, pattern string) (int64, error)
+	// KeySchema 当前 Repo 的 key 命名空间
+	KeySchema() *KeySchema
 	Client() *redis.Client
+	// Ping 检查连接，可用于 core.WithHealthCheck
+	Ping(ctx context.Context) error
 	Close() error
 }
 
 type cacheRepo struct {
 	serverName string
 	client     *redis.Client
+	keySchema  *KeySchema
+	metrics    *commandMetrics
 }
 
 type RedisConf struct {
@@ -37,21 +51,55 @@ type RedisConf struct {
 	MaxRetries   int
 	PoolSize     int
 	MinIdleConns int
+	KeyPrefix    string // key 命名空间前缀，默认为 serverName
+}
+
+type Option func(*option)
+
+type option struct {
+	logger        *zap.Logger
+	slowThreshold time.Duration
+}
+
+// WithLogger 设置慢命令日志的 Logger
+func WithLogger(logger *zap.Logger) Option {
+	return func(opt *option) {
+		opt.logger = logger
+	}
 }
 
-func New(serverName string, cfg *RedisConf) (Repo, error) {
-	client, err := redisConnect(serverName, cfg)
+// WithSlowThreshold 命令耗时超过 threshold 时输出 Warn 日志，需同时设置 WithLogger
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(opt *option) {
+		opt.slowThreshold = threshold
+	}
+}
+
+func New(serverName string, cfg *RedisConf, options ...Option) (Repo, error) {
+	opt := new(option)
+	for _, f := range options {
+		f(opt)
+	}
+
+	client, metrics, err := redisConnect(serverName, cfg, opt)
 	if err != nil {
 		return nil, err
 	}
 
+	keyPrefix := cfg.KeyPrefix
+	if keyPrefix == "" {
+		keyPrefix = serverName
+	}
+
 	return &cacheRepo{
 		serverName: serverName,
 		client:     client,
+		keySchema:  NewKeySchema(keyPrefix),
+		metrics:    metrics,
 	}, nil
 }
 
-func redisConnect(serverName string, cfg *RedisConf) (*redis.Client, error) {
+func redisConnect(serverName string, cfg *RedisConf, opt *option) (*redis.Client, *commandMetrics, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:         cfg.Addr,
 		Password:     cfg.Pass,
@@ -63,15 +111,17 @@ func redisConnect(serverName string, cfg *RedisConf) (*redis.Client, error) {
 	client.AddHook(redisotel.NewTracingHook(redisotel.WithAttributes(
 		attribute.String("servername", serverName),
 	)))
+	metrics := newCommandMetrics(serverName, opt.logger, opt.slowThreshold)
+	client.AddHook(metrics)
 	collect := NewPoolStatsCollector(client, serverName)
 	_ = prometheus.Register(collect)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("ping redis error %w", err)
+		return nil, nil, fmt.Errorf("ping redis error %w", err)
 	}
-	return client, nil
+	return client, metrics, nil
 }
 
 // Set set some <key,value> into redis
@@ -83,10 +133,20 @@ func (c *cacheRepo) Set(ctx context.Context, key, value string, ttl time.Duratio
 	return err
 }
 
+// SetNX set <key,value> only if the key does not exist
+func (c *cacheRepo) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	ok, err := c.client.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis setnx key: %s err: %w ", key, err)
+	}
+	return ok, nil
+}
+
 // Get run the get command from redis
 func (c *cacheRepo) Get(ctx context.Context, key string) (string, error) {
 	var err error
 	value, err := c.client.Get(ctx, key).Result()
+	c.metrics.recordGet(err)
 	if err != nil {
 		err = fmt.Errorf("redis get key: %s err %w", key, err)
 	}
@@ -144,10 +204,46 @@ func (c *cacheRepo) Incr(ctx context.Context, key string) int64 {
 	return value
 }
 
+// DelByPattern 使用 SCAN 遍历删除，不会像 KEYS 一样阻塞 Redis
+func (c *cacheRepo) DelByPattern(ctx context.Context, pattern string) (int64, error) {
+	if pattern == "" || pattern == "*" {
+		return 0, fmt.Errorf("redis del by pattern: %q is not allowed ", pattern)
+	}
+
+	var deleted int64
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, pattern, _ScanCount).Result()
+		if err != nil {
+			return deleted, fmt.Errorf("redis scan pattern: %s err %w", pattern, err)
+		}
+		if len(keys) > 0 {
+			n, err := c.client.Del(ctx, keys...).Result()
+			if err != nil {
+				return deleted, fmt.Errorf("redis del pattern: %s err %w", pattern, err)
+			}
+			deleted += n
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+func (c *cacheRepo) KeySchema() *KeySchema {
+	return c.keySchema
+}
+
 func (c *cacheRepo) Client() *redis.Client {
 	return c.client
 }
 
+func (c *cacheRepo) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
 // Close close redis client
 func (c *cacheRepo) Close() error {
 	return c.client.Close()
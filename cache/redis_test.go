@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRepo(t *testing.T) Repo {
+	server := miniredis.RunT(t)
+	repo, err := New("test", &RedisConf{
+		Addr:      server.Addr(),
+		KeyPrefix: "sk",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = repo.Close()
+	})
+	return repo
+}
+
+func TestCacheRepo_Ping(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	repo, err := New("test", &RedisConf{Addr: server.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	assert.NoError(t, repo.Ping(ctx))
+	server.Close()
+	assert.Error(t, repo.Ping(ctx))
+}
+
+func TestCacheRepo_SetNX(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	ok, err := repo.SetNX(ctx, "sk:lock", "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = repo.SetNX(ctx, "sk:lock", "b", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	v, _ := repo.Get(ctx, "sk:lock")
+	assert.Equal(t, "a", v)
+	ttl, _ := repo.TTL(ctx, "sk:lock")
+	assert.True(t, ttl > 0)
+}
+
+func TestCacheRepo_DelByPattern(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	stock := repo.KeySchema().Template("stock", "product_id")
+	for i := 0; i < 1200; i++ {
+		assert.NoError(t, repo.Set(ctx, stock.Build(i), "1", 0))
+	}
+	assert.NoError(t, repo.Set(ctx, stock.WithVersion(2).Build(1), "1", 0))
+
+	n, err := repo.DelByPattern(ctx, stock.Pattern())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1200), n)
+	assert.True(t, repo.Exists(ctx, stock.WithVersion(2).Build(1)))
+}
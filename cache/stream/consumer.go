@@ -0,0 +1,332 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/cache"
+)
+
+const _AckTimeout = 3 * time.Second
+
+// Consumer 消费组中的一个消费者
+type Consumer interface {
+	Close() error
+}
+
+type consumer struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	client   *redis.Client
+	stream   string
+	group    string
+	name     string
+	handler  Handler
+	cfg      *config
+	logger   *zap.Logger
+	metrics  *StreamMetrics
+	statsKey string
+}
+
+// NewConsumer 新建消费者并开始消费
+// 同一 group 下的多个 consumer 分摊消息，consumer 名称在 group 内需唯一（如：主机名）。
+func NewConsumer(repo cache.Repo, stream, group, name string, h Handler, opts ...Options) (Consumer, error) {
+	cfg, err := newConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &consumer{
+		ctx:     ctx,
+		cancel:  cancel,
+		client:  repo.Client(),
+		stream:  stream,
+		group:   group,
+		name:    name,
+		handler: h,
+		cfg:     cfg,
+		logger: cfg.logger.With(
+			zap.String("stream", stream),
+			zap.String("group", group),
+			zap.String("consumer", name),
+		),
+	}
+
+	if err = createGroup(ctx, c.client, stream, group); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if cfg.shouldMetrics {
+		c.metrics = NewStreamMetrics(cfg.serverName)
+		c.statsKey = registerStats(c.client, stream, group, cfg.serverName)
+	}
+
+	c.wg.Add(2)
+	go c.read()
+	go c.reclaim()
+	return c, nil
+}
+
+// read 读取新消息
+func (c *consumer) read() {
+	defer c.wg.Done()
+
+	for {
+		if c.ctx.Err() != nil {
+			return
+		}
+
+		streams, err := c.client.XReadGroup(c.ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.name,
+			Streams:  []string{c.stream, ">"},
+			Count:    c.cfg.batchSize,
+			Block:    c.cfg.block,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if isClosed(c.ctx, err) {
+				return
+			}
+			c.logger.Error("redis xreadgroup error", zap.Error(err))
+			c.sleep(time.Second)
+			continue
+		}
+
+		for _, s := range streams {
+			for _, m := range s.Messages {
+				c.handle(m, 1)
+			}
+		}
+	}
+}
+
+// reclaim 定期认领空闲过久的 Pending 消息（消费失败或消费者宕机）
+func (c *consumer) reclaim() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.cfg.claimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.claimPending()
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// claimPending 使用 XAUTOCLAIM 认领空闲超过 minIdle 的消息，需要 Redis 6.2 及以上
+// go-redis v8 的 XAutoClaim 只能解析 Redis 6.2 的两段返回，Redis 7 增加了第三段（已删除的消息ID），这里自行解析。
+func (c *consumer) claimPending() {
+	start := "0-0"
+	for {
+		reply, err := c.client.Do(c.ctx, "XAUTOCLAIM", c.stream, c.group, c.name,
+			c.cfg.minIdle.Milliseconds(), start, "COUNT", c.cfg.batchSize).Slice()
+		if err != nil {
+			if !isClosed(c.ctx, err) {
+				c.logger.Error("redis xautoclaim error", zap.Error(err))
+			}
+			return
+		}
+		next, messages := parseAutoClaim(reply)
+
+		retries, err := c.retryCounts(messages)
+		if err != nil {
+			if !isClosed(c.ctx, err) {
+				c.logger.Error("redis xpending error", zap.Error(err))
+			}
+			return
+		}
+
+		for _, m := range messages {
+			retry := retries[m.ID]
+			if c.cfg.maxRetry > 0 && retry > c.cfg.maxRetry {
+				c.dead(m, retry)
+				continue
+			}
+			c.handle(m, retry)
+		}
+
+		if next == "0-0" || next == "" || c.ctx.Err() != nil {
+			return
+		}
+		start = next
+	}
+}
+
+// parseAutoClaim 解析 XAUTOCLAIM 的返回：[next, [[id, [k1, v1, ...]], ...], (Redis 7) [deleted id, ...]]
+// Redis 6.2 中已删除的消息以 nil 返回，直接跳过。
+func parseAutoClaim(reply []interface{}) (string, []redis.XMessage) {
+	if len(reply) < 2 {
+		return "", nil
+	}
+	next, _ := reply[0].(string)
+	entries, _ := reply[1].([]interface{})
+
+	messages := make([]redis.XMessage, 0, len(entries))
+	for _, e := range entries {
+		entry, ok := e.([]interface{})
+		if !ok || len(entry) != 2 {
+			continue
+		}
+		id, _ := entry[0].(string)
+		fields, _ := entry[1].([]interface{})
+		values := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			if key, ok := fields[i].(string); ok {
+				values[key] = fields[i+1]
+			}
+		}
+		messages = append(messages, redis.XMessage{ID: id, Values: values})
+	}
+	return next, messages
+}
+
+// retryCounts 查询消息的投递次数，XAUTOCLAIM 已将投递次数加一
+func (c *consumer) retryCounts(messages []redis.XMessage) (map[string]int64, error) {
+	result := make(map[string]int64, len(messages))
+	if len(messages) == 0 {
+		return result, nil
+	}
+
+	cmds := make([]*redis.XPendingExtCmd, len(messages))
+	_, err := c.client.Pipelined(c.ctx, func(pipe redis.Pipeliner) error {
+		for i, m := range messages {
+			cmds[i] = pipe.XPendingExt(c.ctx, &redis.XPendingExtArgs{
+				Stream: c.stream,
+				Group:  c.group,
+				Start:  m.ID,
+				End:    m.ID,
+				Count:  1,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cmd := range cmds {
+		for _, p := range cmd.Val() {
+			result[p.ID] = p.RetryCount
+		}
+	}
+	return result, nil
+}
+
+// ackContext ACK、转入死信队列使用的 ctx
+// Close 会取消 c.ctx，处理中的消息仍需完成 ACK，否则会被重复投递
+func (c *consumer) ackContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), _AckTimeout)
+}
+
+func (c *consumer) handle(m redis.XMessage, retry int64) {
+	msg := &Message{
+		ID:     m.ID,
+		Stream: c.stream,
+		Values: m.Values,
+		Retry:  retry,
+	}
+
+	err := c.safeHandle(msg)
+	if err != nil {
+		c.logger.Error("stream handler report error",
+			zap.String("id", m.ID),
+			zap.Int64("retry", retry),
+			zap.Error(err),
+		)
+		c.record(_ResultFailed)
+		return
+	}
+
+	ctx, cancel := c.ackContext()
+	defer cancel()
+	if err = c.client.XAck(ctx, c.stream, c.group, m.ID).Err(); err != nil {
+		c.logger.Error("redis xack error", zap.String("id", m.ID), zap.Error(err))
+		return
+	}
+	c.record(_ResultSuccess)
+}
+
+func (c *consumer) safeHandle(msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("stream handler panic: %v", r)
+		}
+	}()
+	return c.handler(c.ctx, msg)
+}
+
+// dead 超过重试次数，转入死信队列并 ACK
+func (c *consumer) dead(m redis.XMessage, retry int64) {
+	c.logger.Warn("stream message exceeds max retry",
+		zap.String("id", m.ID),
+		zap.Int64("retry", retry),
+	)
+
+	ctx, cancel := c.ackContext()
+	defer cancel()
+	if c.cfg.deadLetter != "" {
+		values := make(map[string]interface{}, len(m.Values)+3)
+		for k, v := range m.Values {
+			values[k] = v
+		}
+		values["_origin_stream"] = c.stream
+		values["_origin_id"] = m.ID
+		values["_retry"] = retry
+
+		err := c.client.XAdd(ctx, &redis.XAddArgs{
+			Stream: c.cfg.deadLetter,
+			Values: values,
+		}).Err()
+		if err != nil {
+			c.logger.Error("redis xadd dead letter error", zap.String("id", m.ID), zap.Error(err))
+			return
+		}
+	}
+
+	if err := c.client.XAck(ctx, c.stream, c.group, m.ID).Err(); err != nil {
+		c.logger.Error("redis xack error", zap.String("id", m.ID), zap.Error(err))
+		return
+	}
+	c.record(_ResultDead)
+}
+
+func (c *consumer) record(result string) {
+	if c.metrics != nil {
+		c.metrics.MetricsHandleTotal(c.stream, c.group, result)
+	}
+}
+
+func (c *consumer) sleep(d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-c.ctx.Done():
+	}
+}
+
+func (c *consumer) Close() error {
+	c.cancel()
+	c.wg.Wait()
+
+	if c.statsKey != "" {
+		unregisterStats(c.statsKey)
+	}
+	_ = c.logger.Sync()
+	return nil
+}
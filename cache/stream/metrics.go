@@ -0,0 +1,228 @@
+package stream
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	_ResultSuccess = "success"
+	_ResultFailed  = "failed"
+	_ResultDead    = "dead"
+)
+
+// StreamMetrics 消费结果计数
+type StreamMetrics struct {
+	serverName string
+
+	metricsHandleTotal *prometheus.CounterVec
+}
+
+func NewStreamMetrics(serverName string) *StreamMetrics {
+	if len(serverName) == 0 {
+		serverName = "metrics_stream"
+	}
+	s := &StreamMetrics{serverName: serverName}
+
+	handleTotal := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "go_redis_stream_handle_total",
+			Help: "The total number of stream messages handled, partitioned by result.",
+		},
+		[]string{"system_name", "stream", "group", "result"},
+	)
+
+	// 多个消费者共用同一个指标
+	if err := prometheus.Register(handleTotal); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			handleTotal = are.ExistingCollector.(*prometheus.CounterVec)
+		}
+	}
+	s.metricsHandleTotal = handleTotal
+	return s
+}
+
+func (s *StreamMetrics) MetricsHandleTotal(stream, group, result string) {
+	s.metricsHandleTotal.With(prometheus.Labels{
+		"system_name": s.serverName,
+		"stream":      stream,
+		"group":       group,
+		"result":      result,
+	}).Inc()
+}
+
+// statsRegistry 同一进程内相同 serverName、stream、group 的消费者共用一个 statsCollector
+// 按引用计数注册，最后一个消费者 Close 时才注销。
+var statsRegistry = struct {
+	sync.Mutex
+	refs map[string]*statsRef
+}{refs: make(map[string]*statsRef)}
+
+type statsRef struct {
+	collector prometheus.Collector
+	count     int
+}
+
+func registerStats(client *redis.Client, stream, group, systemName string) string {
+	key := systemName + "\x00" + stream + "\x00" + group
+
+	statsRegistry.Lock()
+	defer statsRegistry.Unlock()
+	if ref, ok := statsRegistry.refs[key]; ok {
+		ref.count++
+		return key
+	}
+
+	ref := &statsRef{collector: NewStatsCollector(client, stream, group, systemName), count: 1}
+	if err := prometheus.Register(ref.collector); err != nil {
+		// 已由外部注册，不归这里管理
+		ref.collector = nil
+	}
+	statsRegistry.refs[key] = ref
+	return key
+}
+
+func unregisterStats(key string) {
+	statsRegistry.Lock()
+	defer statsRegistry.Unlock()
+	ref, ok := statsRegistry.refs[key]
+	if !ok {
+		return
+	}
+	ref.count--
+	if ref.count > 0 {
+		return
+	}
+	if ref.collector != nil {
+		prometheus.Unregister(ref.collector)
+	}
+	delete(statsRegistry.refs, key)
+}
+
+type statsCollector struct {
+	client *redis.Client
+	stream string
+	group  string
+
+	lengthDesc  *prometheus.Desc
+	pendingDesc *prometheus.Desc
+	lagDesc     *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*statsCollector)(nil)
+
+// NewStatsCollector 采集 stream 长度、消费组 Pending 数和消费延迟
+func NewStatsCollector(client *redis.Client, stream, group, systemName string) prometheus.Collector {
+	labels := prometheus.Labels{
+		"system_name": systemName,
+		"stream":      stream,
+		"group":       group,
+	}
+	return &statsCollector{
+		client: client,
+		stream: stream,
+		group:  group,
+		lengthDesc: prometheus.NewDesc(fqName("length"),
+			"Number of entries in the stream.",
+			nil,
+			labels,
+		),
+		pendingDesc: prometheus.NewDesc(fqName("pending"),
+			"Number of entries delivered to the group but not yet acknowledged.",
+			nil,
+			labels,
+		),
+		lagDesc: prometheus.NewDesc(fqName("lag_seconds"),
+			"Time between the newest entry and the last entry delivered to the group.",
+			nil,
+			labels,
+		),
+	}
+}
+
+func fqName(name string) string {
+	return "go_redis_stream_" + name
+}
+
+// Describe implements prometheus.Collector.
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.lengthDesc
+	ch <- c.pendingDesc
+	ch <- c.lagDesc
+}
+
+// Collect implements prometheus.Collector.
+// XINFO 在 Redis 7 中增加了字段，go-redis v8 的 XInfoStream / XInfoGroups 无法解析，这里按 key-value 读取需要的字段。
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	reply, err := c.client.Do(ctx, "XINFO", "STREAM", c.stream).Result()
+	if err != nil {
+		return
+	}
+	info := infoFields(reply)
+	groups, err := c.client.Do(ctx, "XINFO", "GROUPS", c.stream).Slice()
+	if err != nil {
+		return
+	}
+
+	var firstEntryID string
+	if entry, ok := info["first-entry"].([]interface{}); ok && len(entry) > 0 {
+		firstEntryID, _ = entry[0].(string)
+	}
+	lastGeneratedID, _ := info["last-generated-id"].(string)
+
+	ch <- prometheus.MustNewConstMetric(c.lengthDesc, prometheus.GaugeValue, toFloat(info["length"]))
+	for _, reply := range groups {
+		g := infoFields(reply)
+		if name, _ := g["name"].(string); name != c.group {
+			continue
+		}
+		lastDeliveredID, _ := g["last-delivered-id"].(string)
+		ch <- prometheus.MustNewConstMetric(c.pendingDesc, prometheus.GaugeValue, toFloat(g["pending"]))
+		ch <- prometheus.MustNewConstMetric(c.lagDesc, prometheus.GaugeValue, lagSeconds(firstEntryID, lastGeneratedID, lastDeliveredID))
+	}
+}
+
+// infoFields 将 XINFO 返回的 [key1, value1, key2, value2, ...] 转为 map
+func infoFields(reply interface{}) map[string]interface{} {
+	values, _ := reply.([]interface{})
+	fields := make(map[string]interface{}, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		if key, ok := values[i].(string); ok {
+			fields[key] = values[i+1]
+		}
+	}
+	return fields
+}
+
+func toFloat(v interface{}) float64 {
+	n, _ := v.(int64)
+	return float64(n)
+}
+
+// lagSeconds 消息ID 格式为 <毫秒时间戳>-<序号>，以两个 ID 的时间差作为消费延迟
+// 消费组还未投递过消息时，以 stream 中最早的消息作为起点。
+func lagSeconds(firstEntryID, lastGeneratedID, lastDeliveredID string) float64 {
+	generated := idMillis(lastGeneratedID)
+	delivered := idMillis(lastDeliveredID)
+	if delivered == 0 {
+		delivered = idMillis(firstEntryID)
+	}
+	if generated <= delivered {
+		return 0
+	}
+	return float64(generated-delivered) / 1000
+}
+
+func idMillis(id string) int64 {
+	ms, _ := strconv.ParseInt(strings.SplitN(id, "-", 2)[0], 10, 64)
+	return ms
+}
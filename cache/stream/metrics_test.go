@@ -0,0 +1,16 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLagSeconds(t *testing.T) {
+	assert.Equal(t, 1.5, lagSeconds("1000-0", "3500-0", "2000-3"))
+	assert.Equal(t, 0.0, lagSeconds("1000-0", "3500-0", "3500-0"))
+	// 未投递过消息
+	assert.Equal(t, 2.5, lagSeconds("1000-0", "3500-0", "0-0"))
+	// 空 stream
+	assert.Equal(t, 0.0, lagSeconds("", "0-0", "0-0"))
+}
@@ -0,0 +1,184 @@
+package stream
+
+// package stream 基于 Redis Streams 的消息队列
+// 用于秒杀下单等需要异步削峰的场景。
+// Producer 使用 XADD 写入消息，并按 MaxLen 近似裁剪队列长度；
+// Consumer 使用消费组 XREADGROUP 读取，处理成功后 XACK，
+// 处理失败的消息保留在 Pending 列表，由 XAUTOCLAIM 定期认领重试（需要 Redis 6.2 及以上），超过重试次数后转入死信队列。
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/cache"
+)
+
+// Message 消费到的消息
+type Message struct {
+	ID     string
+	Stream string
+	Values map[string]interface{}
+	Retry  int64 // 投递次数，首次投递为 1
+}
+
+// Handler 消息处理函数，返回 nil 时消息被 ACK
+type Handler func(ctx context.Context, msg *Message) error
+
+type config struct {
+	maxLen int64
+
+	batchSize     int64
+	block         time.Duration
+	maxRetry      int64
+	minIdle       time.Duration
+	claimInterval time.Duration
+	deadLetter    string
+
+	logger *zap.Logger
+
+	shouldMetrics bool
+	serverName    string
+}
+
+type Options func(c *config)
+
+// WithMaxLen 队列最大长度（近似裁剪 MAXLEN ~），<=0 不裁剪
+func WithMaxLen(maxLen int64) Options {
+	return func(c *config) {
+		c.maxLen = maxLen
+	}
+}
+
+// WithBatchSize 每次 XREADGROUP / XPENDING 读取的消息数
+func WithBatchSize(n int64) Options {
+	return func(c *config) {
+		c.batchSize = n
+	}
+}
+
+// WithBlock XREADGROUP 阻塞等待时间
+func WithBlock(d time.Duration) Options {
+	return func(c *config) {
+		c.block = d
+	}
+}
+
+// WithMaxRetry 最大投递次数，超过后消息转入死信队列（未配置死信队列则直接 ACK 丢弃）
+func WithMaxRetry(n int64) Options {
+	return func(c *config) {
+		c.maxRetry = n
+	}
+}
+
+// WithClaim 设置 Pending 消息认领：空闲超过 minIdle 的消息每隔 interval 认领一次
+func WithClaim(minIdle, interval time.Duration) Options {
+	return func(c *config) {
+		c.minIdle = minIdle
+		c.claimInterval = interval
+	}
+}
+
+// WithDeadLetter 死信队列 stream 名称
+func WithDeadLetter(stream string) Options {
+	return func(c *config) {
+		c.deadLetter = stream
+	}
+}
+
+func WithLogger(l *zap.Logger) Options {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+// WithMetrics 开启 prometheus 指标，serverName 作为 system_name 标签
+func WithMetrics(serverName string) Options {
+	return func(c *config) {
+		c.shouldMetrics = true
+		c.serverName = serverName
+	}
+}
+
+func newConfig(opts ...Options) (*config, error) {
+	cfg := &config{
+		batchSize:     10,
+		block:         2 * time.Second,
+		maxRetry:      3,
+		minIdle:       30 * time.Second,
+		claimInterval: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.logger == nil {
+		logger, err := zap.NewDevelopment()
+		if err != nil {
+			return nil, err
+		}
+		cfg.logger = logger.Named("redis-stream")
+	}
+	return cfg, nil
+}
+
+// Producer 消息生产者
+type Producer interface {
+	// Publish 写入一条消息，返回消息ID
+	Publish(ctx context.Context, values map[string]interface{}) (string, error)
+}
+
+type producer struct {
+	client *redis.Client
+	stream string
+	cfg    *config
+}
+
+// NewProducer 新建生产者
+func NewProducer(repo cache.Repo, stream string, opts ...Options) (Producer, error) {
+	cfg, err := newConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &producer{
+		client: repo.Client(),
+		stream: stream,
+		cfg:    cfg,
+	}, nil
+}
+
+func (p *producer) Publish(ctx context.Context, values map[string]interface{}) (string, error) {
+	args := &redis.XAddArgs{
+		Stream: p.stream,
+		Values: values,
+	}
+	if p.cfg.maxLen > 0 {
+		args.MaxLen = p.cfg.maxLen
+		args.Approx = true
+	}
+
+	id, err := p.client.XAdd(ctx, args).Result()
+	if err != nil {
+		return "", fmt.Errorf("redis xadd stream: %s err %w", p.stream, err)
+	}
+	return id, nil
+}
+
+// createGroup 创建消费组，stream 不存在时自动创建
+func createGroup(ctx context.Context, client *redis.Client, stream, group string) error {
+	err := client.XGroupCreateMkStream(ctx, stream, group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("redis xgroup create stream: %s group: %s err %w", stream, group, err)
+	}
+	return nil
+}
+
+func isClosed(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || errors.Is(err, redis.ErrClosed)
+}
@@ -0,0 +1,205 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/HYY-yu/seckill.pkg/cache/cachetest"
+)
+
+func TestStream(t *testing.T) {
+	repo, _ := cachetest.NewRepo(t)
+	ctx := context.Background()
+
+	producer, err := NewProducer(repo, "orders", WithMaxLen(100))
+	assert.NoError(t, err)
+
+	var mu sync.Mutex
+	handled := make(map[string]int64)
+	done := make(chan struct{})
+
+	handler := func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		orderId := msg.Values["order_id"].(string)
+		// order 2 首次处理失败，等待重新认领
+		if orderId == "2" && msg.Retry == 1 {
+			return errors.New("db busy")
+		}
+		// order 3 一直失败，进入死信队列
+		if orderId == "3" {
+			panic("bad order")
+		}
+		handled[orderId] = msg.Retry
+		if len(handled) == 2 {
+			close(done)
+		}
+		return nil
+	}
+
+	consumer, err := NewConsumer(repo, "orders", "order_group", "c1", handler,
+		WithBlock(100*time.Millisecond),
+		WithClaim(100*time.Millisecond, 200*time.Millisecond),
+		WithMaxRetry(2),
+		WithDeadLetter("orders:dead"),
+		WithMetrics("test"),
+	)
+	assert.NoError(t, err)
+
+	for _, id := range []string{"1", "2", "3"} {
+		_, err = producer.Publish(ctx, map[string]interface{}{"order_id": id})
+		assert.NoError(t, err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("test timeout. ")
+	}
+
+	// 等待 order 3 超过重试次数
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if repo.Client().XLen(ctx, "orders:dead").Val() == 1 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.NoError(t, consumer.Close())
+
+	mu.Lock()
+	assert.Equal(t, int64(1), handled["1"])
+	assert.Equal(t, int64(2), handled["2"])
+	mu.Unlock()
+
+	dead := repo.Client().XRange(ctx, "orders:dead", "-", "+").Val()
+	if assert.Len(t, dead, 1) {
+		assert.Equal(t, "3", dead[0].Values["order_id"])
+	}
+	pending := repo.Client().XPending(ctx, "orders", "order_group").Val()
+	assert.Equal(t, int64(0), pending.Count)
+}
+
+func TestConsumer_AckOnClose(t *testing.T) {
+	repo, _ := cachetest.NewRepo(t)
+	ctx := context.Background()
+
+	producer, err := NewProducer(repo, "payments")
+	assert.NoError(t, err)
+
+	started := make(chan struct{})
+	handler := func(ctx context.Context, msg *Message) error {
+		close(started)
+		// Close 期间仍在处理
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	}
+	consumer, err := NewConsumer(repo, "payments", "payment_group", "c1", handler, WithBlock(100*time.Millisecond))
+	assert.NoError(t, err)
+
+	_, err = producer.Publish(ctx, map[string]interface{}{"order_id": "1"})
+	assert.NoError(t, err)
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("test timeout. ")
+	}
+	assert.NoError(t, consumer.Close())
+
+	pending := repo.Client().XPending(ctx, "payments", "payment_group").Val()
+	assert.Equal(t, int64(0), pending.Count)
+}
+
+func TestStatsCollector(t *testing.T) {
+	repo, _ := cachetest.NewRepo(t)
+	ctx := context.Background()
+
+	producer, err := NewProducer(repo, "refunds")
+	assert.NoError(t, err)
+	assert.NoError(t, createGroup(ctx, repo.Client(), "refunds", "refund_group"))
+	for i := 0; i < 3; i++ {
+		_, err = producer.Publish(ctx, map[string]interface{}{"order_id": i})
+		assert.NoError(t, err)
+	}
+	// 读取但不 ACK
+	repo.Client().XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    "refund_group",
+		Consumer: "c1",
+		Streams:  []string{"refunds", ">"},
+		Count:    2,
+	})
+
+	c := NewStatsCollector(repo.Client(), "refunds", "refund_group", "test")
+	expected := `
+# HELP go_redis_stream_length Number of entries in the stream.
+# TYPE go_redis_stream_length gauge
+go_redis_stream_length{group="refund_group",stream="refunds",system_name="test"} 3
+# HELP go_redis_stream_pending Number of entries delivered to the group but not yet acknowledged.
+# TYPE go_redis_stream_pending gauge
+go_redis_stream_pending{group="refund_group",stream="refunds",system_name="test"} 2
+`
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected),
+		fqName("length"), fqName("pending")))
+}
+
+func TestParseAutoClaim(t *testing.T) {
+	// Redis 7 返回第三段已删除的消息ID，Redis 6.2 中已删除的消息为 nil
+	reply := []interface{}{
+		"1650000000000-3",
+		[]interface{}{
+			[]interface{}{"1650000000000-1", []interface{}{"order_id", "1"}},
+			nil,
+		},
+		[]interface{}{"1650000000000-2"},
+	}
+	next, messages := parseAutoClaim(reply)
+	assert.Equal(t, "1650000000000-3", next)
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, "1650000000000-1", messages[0].ID)
+		assert.Equal(t, map[string]interface{}{"order_id": "1"}, messages[0].Values)
+	}
+}
+
+func TestRegisterStats(t *testing.T) {
+	repo, _ := cachetest.NewRepo(t)
+	handler := func(ctx context.Context, msg *Message) error { return nil }
+
+	c1, err := NewConsumer(repo, "refunds", "refund_group", "c1", handler, WithBlock(100*time.Millisecond), WithMetrics("stats_test"))
+	assert.NoError(t, err)
+	c2, err := NewConsumer(repo, "refunds", "refund_group", "c2", handler, WithBlock(100*time.Millisecond), WithMetrics("stats_test"))
+	assert.NoError(t, err)
+
+	registered := func() bool {
+		mfs, err := prometheus.DefaultGatherer.Gather()
+		assert.NoError(t, err)
+		for _, mf := range mfs {
+			if mf.GetName() == fqName("length") {
+				for _, m := range mf.GetMetric() {
+					for _, l := range m.GetLabel() {
+						if l.GetName() == "system_name" && l.GetValue() == "stats_test" {
+							return true
+						}
+					}
+				}
+			}
+		}
+		return false
+	}
+
+	assert.True(t, registered())
+	// 第一个消费者 Close 后仍保留
+	assert.NoError(t, c1.Close())
+	assert.True(t, registered())
+	assert.NoError(t, c2.Close())
+	assert.False(t, registered())
+}
@@ -3,6 +3,8 @@ package core
 import (
 	"bytes"
 	stdContext "context"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -22,9 +24,12 @@ type HandlerFunc func(c Context)
 const (
 	_Logger     = "_logger_"
 	_Response   = "_response_"
+	_RespMeta   = "_response_meta_"
+	_Renderer   = "_renderer_"
 	_UserID     = "_user_id_"
 	_UserName   = "_user_name_"
 	_DisableLog = "_disable_log_"
+	_Drained    = "_drained_"
 )
 
 var contextPool = &sync.Pool{
@@ -62,6 +67,10 @@ type Context interface {
 	// tag: `uri:"xxx"`
 	ShouldBindURI(obj interface{}) error
 
+	// BindAndValidate 依次反序列化 path 参数、querystring 和 postForm、postJson，再按 `v` tag 校验
+	// 失败时已返回 400 ParamBindError，Data 为 []response.FieldError，调用方直接 return 即可
+	BindAndValidate(obj interface{}) error
+
 	// Header 获取 Header 对象
 	Header() http.Header
 	// GetHeader 获取 Header
@@ -79,11 +88,26 @@ type Context interface {
 
 	// Payload 正确返回
 	Payload(payload interface{})
+	// PayloadWithCode 正确返回并指定 HTTP 状态码，如 201；204、304 不返回 body
+	PayloadWithCode(httpCode int, payload interface{})
 	getResponse() interface{}
+	getResponseMeta() *responseMeta
+	// Response 已返回的 HTTP 状态码与 body（由 ResponseRenderer 构造），未返回时 ok 为 false，流式返回时 body 为 nil
+	// 在中间件中 RequestContext().Next() 之后调用
+	Response() (httpCode int, body interface{}, ok bool)
+	// Replay 原样返回已构造好的 body，不经过 ResponseRenderer，如重放缓存的返回
+	Replay(httpCode int, body interface{})
 
 	// HTML 返回界面
 	HTML(name string, obj interface{})
 
+	// SSE 以 text/event-stream 推送事件，带心跳，客户端断开后 send 返回 error
+	SSE(handler func(send SSESend) error, options ...SSEOption)
+	// Stream 分块返回 reader 的内容
+	Stream(reader io.Reader, contentType string) error
+	// File 返回文件
+	File(filepath string)
+
 	// AbortWithError 错误返回
 	AbortWithError(err error)
 
@@ -131,6 +155,43 @@ func (c *context) ShouldBindURI(obj interface{}) error {
 	return c.ctx.ShouldBindUri(obj)
 }
 
+// BindAndValidate 绑定并校验参数，失败时已 Abort
+func (c *context) BindAndValidate(obj interface{}) error {
+	if err := c.bind(obj); err != nil {
+		c.AbortWithError(response.NewErrorAutoMsg(
+			http.StatusBadRequest,
+			response.ParamBindError,
+		).WithErr(err))
+		return err
+	}
+
+	if details := validate(c.ctx.Request.Context(), obj); len(details) > 0 {
+		c.AbortWithError(response.NewErrorAutoMsg(
+			http.StatusBadRequest,
+			response.ParamBindError,
+		).WithData(details))
+		return errors.New(details[0].Message)
+	}
+	return nil
+}
+
+func (c *context) bind(obj interface{}) error {
+	if len(c.ctx.Params) > 0 {
+		if err := c.ShouldBindURI(obj); err != nil {
+			return err
+		}
+	}
+	if err := c.ShouldBindForm(obj); err != nil {
+		return err
+	}
+	if c.ctx.ContentType() == binding.MIMEJSON {
+		if body := c.RequestData(); len(body) > 0 {
+			return binding.JSON.BindBody(body, obj)
+		}
+	}
+	return nil
+}
+
 // Redirect 重定向
 func (c *context) Redirect(code int, location string) {
 	c.ctx.Redirect(code, location)
@@ -149,13 +210,60 @@ func (c *context) setLogger(logger *zap.Logger) {
 }
 
 func (c *context) Payload(payload interface{}) {
-	resp := response.NewResponse(payload)
+	c.PayloadWithCode(http.StatusOK, payload)
+}
 
+func (c *context) PayloadWithCode(httpCode int, payload interface{}) {
 	if _, exist := c.ctx.Get(_Response); exist {
 		return
 	}
-	c.ctx.JSON(http.StatusOK, resp)
-	c.ctx.Set(_Response, resp)
+
+	body := c.renderer().Success(payload)
+	c.ctx.Set(_Response, body)
+	c.ctx.Set(_RespMeta, &responseMeta{httpCode: httpCode})
+	if !bodyAllowed(httpCode) {
+		c.ctx.Status(httpCode)
+		c.ctx.Writer.WriteHeaderNow()
+		return
+	}
+	c.render(httpCode, body, payload)
+}
+
+func (c *context) renderer() ResponseRenderer {
+	if r, ok := c.ctx.Get(_Renderer); ok {
+		return r.(ResponseRenderer)
+	}
+	return jsonResponseRenderer{}
+}
+
+// render 按 Accept 选择编码，客户端要求的格式无法编码时返回 406
+func (c *context) render(httpCode int, body, payload interface{}) {
+	format := c.ctx.NegotiateFormat(_Offers...)
+	raw, contentType, err := encode(format, body, payload)
+	switch {
+	case err == nil:
+		c.ctx.Data(httpCode, contentType, raw)
+	case errors.Is(err, errUseJSON):
+		c.ctx.JSON(httpCode, body)
+	default:
+		if logger := c.Logger(); logger != nil {
+			logger.Warn("render response failed", zap.String("format", format), zap.Error(err))
+		}
+		if meta, ok := c.ctx.Get(_RespMeta); ok {
+			meta.(*responseMeta).httpCode = http.StatusNotAcceptable
+		}
+		c.ctx.AbortWithStatus(http.StatusNotAcceptable)
+	}
+}
+
+func bodyAllowed(httpCode int) bool {
+	switch {
+	case httpCode >= 100 && httpCode <= 199:
+		return false
+	case httpCode == http.StatusNoContent, httpCode == http.StatusNotModified:
+		return false
+	}
+	return true
 }
 
 func (c *context) getResponse() interface{} {
@@ -165,6 +273,39 @@ func (c *context) getResponse() interface{} {
 	return nil
 }
 
+func (c *context) getResponseMeta() *responseMeta {
+	if meta, ok := c.ctx.Get(_RespMeta); ok {
+		return meta.(*responseMeta)
+	}
+	return nil
+}
+
+func (c *context) Response() (int, interface{}, bool) {
+	meta := c.getResponseMeta()
+	if meta == nil {
+		return 0, nil, false
+	}
+	return meta.httpCode, c.getResponse(), true
+}
+
+func (c *context) Replay(httpCode int, body interface{}) {
+	meta := &responseMeta{httpCode: httpCode, replayed: true}
+	if resp, ok := body.(*response.JsonResponse); ok {
+		meta.businessCode = resp.Code
+		meta.message = resp.Message
+	}
+
+	c.ctx.Abort()
+	c.ctx.Set(_Response, body)
+	c.ctx.Set(_RespMeta, meta)
+	if body == nil || !bodyAllowed(httpCode) {
+		c.ctx.Status(httpCode)
+		c.ctx.Writer.WriteHeaderNow()
+		return
+	}
+	c.render(httpCode, body, nil)
+}
+
 func (c *context) HTML(name string, obj interface{}) {
 	c.ctx.HTML(200, name+".html", obj)
 }
@@ -187,13 +328,15 @@ func (c *context) AbortWithError(err error) {
 			httpCode = http.StatusInternalServerError
 		}
 
-		resp := response.NewResponse()
-		resp.Code = errResp.GetBusinessCode()
-		resp.Message = errResp.GetMsg()
-
-		c.ctx.AbortWithStatus(httpCode)
-		c.ctx.Set(_Response, resp)
-		c.ctx.JSON(httpCode, resp)
+		body := c.renderer().Error(errResp)
+		c.ctx.Abort()
+		c.ctx.Set(_Response, body)
+		c.ctx.Set(_RespMeta, &responseMeta{
+			httpCode:     httpCode,
+			businessCode: errResp.GetBusinessCode(),
+			message:      errResp.GetMsg(),
+		})
+		c.render(httpCode, body, nil)
 	}
 }
 
@@ -285,17 +428,42 @@ func (s *svcContext) Logger() *zap.Logger {
 	return s.logger
 }
 
-func (c *context) SvcContext() SvcContext {
-	ctx := c.RequestContext().Request.Context()
+type svcContextKey struct{}
 
-	// 用户信息设置进去
-	ctx = stdContext.WithValue(ctx, _UserID, c.UserID())
-	ctx = stdContext.WithValue(ctx, _UserName, c.UserName())
+// NewSvcContext 新建 SvcContext，用于没有 HTTP 请求的场景（如定时任务、消息消费）
+// 返回的 SvcContext.Context() 中携带了自身，下层可通过 SvcContextFromContext 取回
+func NewSvcContext(ctx stdContext.Context, userID int64, userName string, logger *zap.Logger) SvcContext {
+	ctx = stdContext.WithValue(ctx, _UserID, userID)
+	ctx = stdContext.WithValue(ctx, _UserName, userName)
+
+	svc := &svcContext{
+		logger: logger,
+	}
+	svc.ctx = stdContext.WithValue(ctx, svcContextKey{}, svc)
+	return svc
+}
 
-	return &svcContext{
-		ctx:    ctx,
-		logger: c.Logger(),
+// SvcContextFromContext 从 SvcContext.Context() 派生的 ctx 中取回 SvcContext
+func SvcContextFromContext(ctx stdContext.Context) (SvcContext, bool) {
+	if ctx == nil {
+		return nil, false
 	}
+	svc, ok := ctx.Value(svcContextKey{}).(SvcContext)
+	return svc, ok
+}
+
+// Operator 从 SvcContext.Context() 派生的 ctx 中取出操作人，可作为 db.DBConfig 的 AuditOperator
+func Operator(ctx stdContext.Context) (userID int64, userName string, ok bool) {
+	svc, ok := SvcContextFromContext(ctx)
+	if !ok || svc.UserId() == 0 {
+		return 0, "", false
+	}
+	return svc.UserId(), svc.UserName(), true
+}
+
+func (c *context) SvcContext() SvcContext {
+	// 用户信息设置进去
+	return NewSvcContext(c.RequestContext().Request.Context(), c.UserID(), c.UserName(), c.Logger())
 }
 
 // URI unescape后的uri
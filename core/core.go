@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-contrib/pprof"
@@ -24,13 +27,17 @@ const _MaxBurstSize = 100000
 type Option func(*option)
 
 type option struct {
-	disablePProf      bool
-	disableSwagger    bool
-	disablePrometheus bool
-	panicNotify       OnPanicNotify
-	recordMetrics     RecordMetrics
-	enableCors        bool
-	enableRate        bool
+	disablePProf       bool
+	disableSwagger     bool
+	disablePrometheus  bool
+	panicNotify        OnPanicNotify
+	recordMetrics      RecordMetrics
+	enableCors         bool
+	enableRate         bool
+	healthChecks       []healthCheck
+	healthCheckTimeout time.Duration
+	healthCheckCache   *time.Duration
+	renderer           ResponseRenderer
 }
 
 // OnPanicNotify 发生panic时通知用
@@ -84,10 +91,13 @@ func WithEnableRate() Option {
 	}
 }
 
+// AuthHandler 鉴权函数，返回当前用户
+type AuthHandler func(Context) (userID int64, userName string, err response.Error)
+
 // WrapAuthHandler 用来处理 Auth 的入口，在之后的handler中只需 ctx.UserID() ctx.UserName() 即可。
-// handler 是真正的处理者
-func WrapAuthHandler(handler func(Context) (userID int64, userName string, err response.Error)) HandlerFunc {
-	return func(ctx Context) {
+// handler 是真正的处理者，需要在 OpenAPI 文档中标记鉴权时使用 RouterGroup.UseAuth
+func WrapAuthHandler(handler AuthHandler) HandlerFunc {
+	h := func(ctx Context) {
 		userID, userName, err := handler(ctx)
 		if err != nil {
 			ctx.AbortWithError(err)
@@ -96,13 +106,21 @@ func WrapAuthHandler(handler func(Context) (userID int64, userName string, err r
 		ctx.setUserID(userID)
 		ctx.setUserName(userName)
 	}
+	return h
 }
 
 // RouterGroup 包装gin的RouterGroup
 type RouterGroup interface {
 	Group(string, ...HandlerFunc) RouterGroup
 	Use(...HandlerFunc)
+	// UseAuth 同 Use(WrapAuthHandler(handler))，之后注册的路由在 OpenAPI 文档中标记为需要鉴权
+	UseAuth(handler AuthHandler)
 	IRoutes
+	// Handle 注册 core.Handle 返回的 Endpoint，middlewares 在 endpoint 之前执行，
+	// 请求与返回类型记录到 OpenAPI 文档
+	Handle(httpMethod, relativePath string, endpoint *Endpoint, middlewares ...HandlerFunc)
+	// WS 注册 WebSocket 路由，在组内中间件执行后升级连接
+	WS(relativePath string, handler WSHandler, options ...WSOption)
 }
 
 var _ IRoutes = (*router)(nil)
@@ -120,48 +138,96 @@ type IRoutes interface {
 }
 
 type router struct {
-	group *gin.RouterGroup
+	group  *gin.RouterGroup
+	routes *routeRegistry
+	hub    *wsHub
+	auth   bool // 组内的路由需要鉴权
 }
 
 func (r *router) Group(relativePath string, handlers ...HandlerFunc) RouterGroup {
 	group := r.group.Group(relativePath, wrapHandlers(handlers...)...)
-	return &router{group: group}
+	return &router{group: group, routes: r.routes, hub: r.hub, auth: r.auth}
 }
 
 func (r *router) Use(handlers ...HandlerFunc) {
 	r.group.Use(wrapHandlers(handlers...)...)
 }
 
+func (r *router) UseAuth(handler AuthHandler) {
+	r.Use(WrapAuthHandler(handler))
+	r.auth = true
+}
+
+func (r *router) Handle(httpMethod, relativePath string, endpoint *Endpoint, middlewares ...HandlerFunc) {
+	handlers := make([]HandlerFunc, 0, len(middlewares)+1)
+	handlers = append(handlers, middlewares...)
+	handlers = append(handlers, endpoint.handler)
+	r.group.Handle(httpMethod, relativePath, wrapHandlers(handlers...)...)
+	r.record(httpMethod, relativePath, endpoint.meta)
+}
+
 func (r *router) Any(relativePath string, handlers ...HandlerFunc) {
 	r.group.Any(relativePath, wrapHandlers(handlers...)...)
+	for _, method := range []string{
+		http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+	} {
+		r.record(method, relativePath, nil)
+	}
 }
 
 func (r *router) GET(relativePath string, handlers ...HandlerFunc) {
 	r.group.GET(relativePath, wrapHandlers(handlers...)...)
+	r.record(http.MethodGet, relativePath, nil)
 }
 
 func (r *router) POST(relativePath string, handlers ...HandlerFunc) {
 	r.group.POST(relativePath, wrapHandlers(handlers...)...)
+	r.record(http.MethodPost, relativePath, nil)
 }
 
 func (r *router) DELETE(relativePath string, handlers ...HandlerFunc) {
 	r.group.DELETE(relativePath, wrapHandlers(handlers...)...)
+	r.record(http.MethodDelete, relativePath, nil)
 }
 
 func (r *router) PATCH(relativePath string, handlers ...HandlerFunc) {
 	r.group.PATCH(relativePath, wrapHandlers(handlers...)...)
+	r.record(http.MethodPatch, relativePath, nil)
 }
 
 func (r *router) PUT(relativePath string, handlers ...HandlerFunc) {
 	r.group.PUT(relativePath, wrapHandlers(handlers...)...)
+	r.record(http.MethodPut, relativePath, nil)
 }
 
 func (r *router) OPTIONS(relativePath string, handlers ...HandlerFunc) {
 	r.group.OPTIONS(relativePath, wrapHandlers(handlers...)...)
+	r.record(http.MethodOptions, relativePath, nil)
 }
 
 func (r *router) HEAD(relativePath string, handlers ...HandlerFunc) {
 	r.group.HEAD(relativePath, wrapHandlers(handlers...)...)
+	r.record(http.MethodHead, relativePath, nil)
+}
+
+// record 记录路由用于生成 OpenAPI 文档，meta 为空时只记录方法与路径
+func (r *router) record(method, relativePath string, meta *HandlerMeta) {
+	if r.routes == nil {
+		return
+	}
+	r.routes.add(routeInfo{
+		method: method,
+		path:   joinPath(r.group.BasePath(), relativePath),
+		meta:   meta,
+		auth:   r.auth,
+	})
+}
+
+func joinPath(basePath, relativePath string) string {
+	if relativePath == "" {
+		return basePath
+	}
+	return strings.TrimSuffix(basePath, "/") + "/" + strings.TrimPrefix(relativePath, "/")
 }
 
 func wrapHandlers(handlers ...HandlerFunc) []gin.HandlerFunc {
@@ -184,11 +250,23 @@ var _ Engine = (*engine)(nil)
 type Engine interface {
 	http.Handler
 	Group(relativePath string) RouterGroup
+	// Hub WebSocket 连接管理，按用户或 topic 推送
+	Hub() WSHub
+
+	logger() *zap.Logger
+	setDraining(draining bool)
+	closeWS()
 }
 
 type engine struct {
 	e         *gin.Engine
 	baseGroup *gin.RouterGroup // 全局basePath
+	zap       *zap.Logger
+	routes    *routeRegistry
+	hub       *wsHub
+	draining  int32         // Server 优雅关闭中，健康检查返回 draining
+	drained   chan struct{} // 开始优雅关闭时关闭，结束 SSE 等长连接
+	drainOnce sync.Once
 }
 
 func (m *engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -197,8 +275,37 @@ func (m *engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 func (m *engine) Group(relativePath string) RouterGroup {
 	return &router{
-		group: m.baseGroup.Group(relativePath),
+		group:  m.baseGroup.Group(relativePath),
+		routes: m.routes,
+		hub:    m.hub,
+	}
+}
+
+func (m *engine) Hub() WSHub {
+	return m.hub
+}
+
+func (m *engine) closeWS() {
+	m.hub.closeAll()
+}
+
+func (m *engine) logger() *zap.Logger {
+	return m.zap
+}
+
+func (m *engine) setDraining(draining bool) {
+	var v int32
+	if draining {
+		v = 1
+		m.drainOnce.Do(func() {
+			close(m.drained)
+		})
 	}
+	atomic.StoreInt32(&m.draining, v)
+}
+
+func (m *engine) isDraining() bool {
+	return atomic.LoadInt32(&m.draining) == 1
 }
 
 func New(serverName string, logger *zap.Logger, options ...Option) (Engine, error) {
@@ -208,7 +315,11 @@ func New(serverName string, logger *zap.Logger, options ...Option) (Engine, erro
 
 	gin.SetMode(gin.DebugMode)
 	mux := &engine{
-		e: gin.New(),
+		e:       gin.New(),
+		zap:     logger,
+		routes:  new(routeRegistry),
+		hub:     newWSHub(serverName),
+		drained: make(chan struct{}),
 	}
 	// 全部url以 serverName开头 ： /serverName/metrics
 	basePath := "/" + serverName
@@ -219,12 +330,21 @@ func New(serverName string, logger *zap.Logger, options ...Option) (Engine, erro
 		f(opt)
 	}
 
+	if opt.renderer != nil {
+		renderer := opt.renderer
+		mux.baseGroup.Use(func(ctx *gin.Context) {
+			ctx.Set(_Renderer, renderer)
+		})
+	}
+
 	if !opt.disablePProf {
 		pprof.RouteRegister(mux.baseGroup) // register pprof to gin
 	}
 
 	if !opt.disableSwagger {
-		mux.baseGroup.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler)) // register swagger
+		// 由注册的路由生成 OpenAPI 文档，swagger UI 使用此文档
+		mux.baseGroup.GET("/openapi.json", wrapHandlers(openAPIHandler(serverName, basePath, mux.routes))...)
+		mux.baseGroup.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL(basePath+"/openapi.json"))) // register swagger
 	}
 
 	if !opt.disablePrometheus {
@@ -255,6 +375,7 @@ func New(serverName string, logger *zap.Logger, options ...Option) (Engine, erro
 
 		// 注入Logger到Ctx
 		c.setLogger(logger)
+		ctx.Set(_Drained, mux.drained)
 
 		defer func() {
 			if err := recover(); err != nil {
@@ -292,18 +413,10 @@ func New(serverName string, logger *zap.Logger, options ...Option) (Engine, erro
 	system := mux.Group("/system")
 	{
 		// 健康检查
-		system.GET("/health", func(ctx Context) {
-			resp := &struct {
-				Timestamp time.Time `json:"timestamp"`
-				Host      string    `json:"host"`
-				Status    string    `json:"status"`
-			}{
-				Timestamp: time.Now(),
-				Host:      ctx.RequestContext().Request.Host,
-				Status:    "ok",
-			}
-			ctx.Payload(resp)
-		})
+		checker := newHealthChecker(opt)
+		system.GET("/livez", livezHandler())
+		system.GET("/readyz", readyzHandler(checker, mux.isDraining))
+		system.GET("/health", readyzHandler(checker, mux.isDraining))
 	}
 
 	// 注册全局 Telemetry
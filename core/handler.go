@@ -0,0 +1,168 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+// Handle 将业务函数包装为 Endpoint，自动完成参数绑定校验、错误转换和返回
+// fn 支持以下签名，Req 必须为结构体指针：
+//
+//	func(svc core.SvcContext, req *Req) (*Resp, error)
+//	func(svc core.SvcContext, req *Req) error
+//	func(svc core.SvcContext) (*Resp, error)
+//	func(svc core.SvcContext) error
+//
+// 绑定与校验同 Context.BindAndValidate，返回的 error 为（或包装了）response.Error 时按其返回，否则返回 500。
+// 通过 RouterGroup.Handle 注册时请求与返回类型会被记录，用于生成 OpenAPI 文档。签名错误时 panic。
+//
+//	g.Handle(http.MethodPost, "/orders", core.Handle(orderSvc.Create, core.WithSummary("创建订单"), core.WithTags("order")))
+func Handle(fn interface{}, options ...HandleOption) *Endpoint {
+	fv := reflect.ValueOf(fn)
+	meta, err := parseHandler(fv.Type())
+	if err != nil {
+		panic(fmt.Sprintf("core.Handle %s: %s", fv.Type(), err))
+	}
+	for _, f := range options {
+		f(meta)
+	}
+
+	hasReq := meta.Request != nil
+	hasResp := meta.Response != nil
+	h := func(ctx Context) {
+		args := []reflect.Value{reflect.ValueOf(ctx.SvcContext())}
+		if hasReq {
+			req := reflect.New(meta.Request)
+			if err := ctx.BindAndValidate(req.Interface()); err != nil {
+				return
+			}
+			args = append(args, req)
+		}
+
+		out := fv.Call(args)
+		if errValue := out[len(out)-1]; !errValue.IsNil() {
+			abortWithHandleError(ctx, errValue.Interface().(error))
+			return
+		}
+
+		var payload interface{}
+		if hasResp && !isNilValue(out[0]) {
+			payload = out[0].Interface()
+		}
+		ctx.Payload(payload)
+	}
+
+	return &Endpoint{handler: h, meta: meta}
+}
+
+// Endpoint core.Handle 包装后的业务函数与其元数据
+type Endpoint struct {
+	handler HandlerFunc
+	meta    *HandlerMeta
+}
+
+// HandlerFunc 不需要记录文档时，可以作为普通的 HandlerFunc 注册
+func (e *Endpoint) HandlerFunc() HandlerFunc {
+	return e.handler
+}
+
+// Meta 请求、返回类型等元数据
+func (e *Endpoint) Meta() *HandlerMeta {
+	return e.meta
+}
+
+// HandlerMeta 处理函数的元数据
+type HandlerMeta struct {
+	// Request 请求参数类型（结构体），nil 表示没有参数
+	Request reflect.Type
+	// Response 返回的 Data 类型，nil 表示没有返回
+	Response reflect.Type
+	Summary  string
+	Tags     []string
+	// Errors 可能返回的业务错误
+	Errors []response.Error
+}
+
+type HandleOption func(*HandlerMeta)
+
+// WithSummary 接口说明
+func WithSummary(summary string) HandleOption {
+	return func(meta *HandlerMeta) {
+		meta.Summary = summary
+	}
+}
+
+// WithTags 接口分组
+func WithTags(tags ...string) HandleOption {
+	return func(meta *HandlerMeta) {
+		meta.Tags = append(meta.Tags, tags...)
+	}
+}
+
+// WithErrors 可能返回的业务错误，写入文档
+func WithErrors(errs ...response.Error) HandleOption {
+	return func(meta *HandlerMeta) {
+		meta.Errors = append(meta.Errors, errs...)
+	}
+}
+
+var (
+	svcContextType = reflect.TypeOf((*SvcContext)(nil)).Elem()
+	errorType      = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+func parseHandler(t reflect.Type) (*HandlerMeta, error) {
+	if t.Kind() != reflect.Func {
+		return nil, errors.New("handler must be a func")
+	}
+
+	meta := new(HandlerMeta)
+	switch t.NumIn() {
+	case 2:
+		req := t.In(1)
+		if req.Kind() != reflect.Ptr || req.Elem().Kind() != reflect.Struct {
+			return nil, errors.New("the second param must be a pointer to struct")
+		}
+		meta.Request = req.Elem()
+		fallthrough
+	case 1:
+		if t.In(0) != svcContextType {
+			return nil, errors.New("the first param must be core.SvcContext")
+		}
+	default:
+		return nil, errors.New("handler must have 1 or 2 params")
+	}
+
+	switch t.NumOut() {
+	case 2:
+		meta.Response = t.Out(0)
+		fallthrough
+	case 1:
+		if t.Out(t.NumOut()-1) != errorType {
+			return nil, errors.New("the last result must be error")
+		}
+	default:
+		return nil, errors.New("handler must have 1 or 2 results")
+	}
+	return meta, nil
+}
+
+func abortWithHandleError(ctx Context, err error) {
+	var respErr response.Error
+	if errors.As(err, &respErr) {
+		ctx.AbortWithError(respErr)
+		return
+	}
+	ctx.AbortWithError(err)
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
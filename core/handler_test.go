@@ -0,0 +1,93 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+type getUserReq struct {
+	ID int64 `uri:"id" v:"min:1"`
+}
+
+type getUserResp struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+var errUserNotFound = response.NewErrorAutoMsg(http.StatusNotFound, response.ServerError)
+
+func getUser(svc SvcContext, req *getUserReq) (*getUserResp, error) {
+	switch req.ID {
+	case 404:
+		return nil, fmt.Errorf("get user: %w", errUserNotFound)
+	case 500:
+		return nil, errors.New("db down")
+	}
+	return &getUserResp{ID: req.ID, Name: svc.UserName()}, nil
+}
+
+func TestHandle(t *testing.T) {
+	mux, err := New("svc", zap.NewNop(), WithDisablePProf(), WithDisableSwagger(), WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Handle(getUser, WithSummary("获取用户"), WithTags("user"))
+	g := mux.Group("/api")
+	g.UseAuth(func(ctx Context) (int64, string, response.Error) {
+		return 1, "alice", nil
+	})
+	g.Handle(http.MethodGet, "/users/:id", h)
+	g.DELETE("/users/:id", Handle(func(svc SvcContext) error { return nil }).HandlerFunc())
+
+	do := func(method, path string) (int, *response.JsonResponse) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, "/svc/api/users/"+path, strings.NewReader("")))
+		resp := new(response.JsonResponse)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		return w.Code, resp
+	}
+
+	code, resp := do(http.MethodGet, "7")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"id": float64(7), "name": "alice"}, resp.Data)
+
+	code, resp = do(http.MethodGet, "0")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, response.ParamBindError, resp.Code)
+
+	// 包装的 response.Error 按其返回
+	code, _ = do(http.MethodGet, "404")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, resp = do(http.MethodGet, "500")
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, response.ServerError, resp.Code)
+
+	code, resp = do(http.MethodDelete, "7")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{}, resp.Data)
+
+	meta := h.Meta()
+	assert.Equal(t, reflect.TypeOf(getUserReq{}), meta.Request)
+	assert.Equal(t, reflect.TypeOf(&getUserResp{}), meta.Response)
+	assert.Equal(t, "获取用户", meta.Summary)
+	assert.Equal(t, []string{"user"}, meta.Tags)
+}
+
+func TestHandle_InvalidSignature(t *testing.T) {
+	assert.Panics(t, func() { Handle(func(ctx Context) {}) })
+	assert.Panics(t, func() { Handle(func(svc SvcContext, req getUserReq) error { return nil }) })
+	assert.Panics(t, func() { Handle(func(svc SvcContext) *getUserResp { return nil }) })
+	assert.Panics(t, func() { Handle("handler") })
+}
@@ -0,0 +1,220 @@
+package core
+
+import (
+	stdContext "context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+// 健康检查
+// /system/livez  进程存活即返回 200，不检查依赖，用于 livenessProbe
+// /system/readyz 并发执行所有依赖检查，任一失败或优雅关闭中返回 503，用于 readinessProbe
+// /system/health 同 readyz，兼容旧的探针配置
+//
+//	core.New("seckill", logger,
+//		core.WithHealthCheck("db", dbRepo.Ping),
+//		core.WithHealthCheck("redis", cacheRepo.Ping),
+//		core.WithHealthCheck("etcd", job.Ping),
+//	)
+
+const (
+	_DefaultHealthCheckTimeout = 3 * time.Second
+	_DefaultHealthCheckCache   = time.Second
+
+	HealthStatusOk       = "ok"
+	HealthStatusFail     = "fail"
+	HealthStatusDraining = "draining"
+)
+
+var errHealthCheckTimeout = errors.New("health check timeout")
+
+// HealthCheck 依赖组件的健康检查，如 db.Repo.Ping、cache.Repo.Ping
+type HealthCheck func(ctx stdContext.Context) error
+
+type healthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// WithHealthCheck 注册依赖组件的健康检查
+func WithHealthCheck(name string, check HealthCheck) Option {
+	return func(opt *option) {
+		opt.healthChecks = append(opt.healthChecks, healthCheck{name: name, check: check})
+	}
+}
+
+// WithHealthCheckTimeout 单个检查的超时时间，默认 3s
+func WithHealthCheckTimeout(d time.Duration) Option {
+	return func(opt *option) {
+		opt.healthCheckTimeout = d
+	}
+}
+
+// WithHealthCheckCache 检查结果的缓存时间，避免探针频繁访问依赖，默认 1s，为 0 时不缓存
+func WithHealthCheckCache(ttl time.Duration) Option {
+	return func(opt *option) {
+		opt.healthCheckCache = &ttl
+	}
+}
+
+type healthResult struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type healthReport struct {
+	Timestamp time.Time               `json:"timestamp"`
+	Host      string                  `json:"host"`
+	Status    string                  `json:"status"`
+	Checks    map[string]healthResult `json:"checks,omitempty"`
+}
+
+type healthChecker struct {
+	checks   []healthCheck
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	results   map[string]healthResult
+	healthy   bool
+	checkedAt time.Time
+}
+
+func newHealthChecker(opt *option) *healthChecker {
+	h := &healthChecker{
+		checks:   opt.healthChecks,
+		timeout:  _DefaultHealthCheckTimeout,
+		cacheTTL: _DefaultHealthCheckCache,
+	}
+	if opt.healthCheckTimeout > 0 {
+		h.timeout = opt.healthCheckTimeout
+	}
+	if opt.healthCheckCache != nil {
+		h.cacheTTL = *opt.healthCheckCache
+	}
+	return h
+}
+
+// check 返回缓存时间内的结果，过期后重新执行，同一时间只有一次检查在执行
+func (h *healthChecker) check() (map[string]healthResult, bool) {
+	if len(h.checks) == 0 {
+		return nil, true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.results != nil && time.Since(h.checkedAt) < h.cacheTTL {
+		return h.results, h.healthy
+	}
+	h.results, h.healthy = h.run()
+	h.checkedAt = time.Now()
+	return h.results, h.healthy
+}
+
+// run 并发执行检查，结果会被缓存，不使用请求的 ctx 以免客户端断开导致检查失败
+func (h *healthChecker) run() (map[string]healthResult, bool) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		healthy = true
+		results = make(map[string]healthResult, len(h.checks))
+	)
+	for _, hc := range h.checks {
+		wg.Add(1)
+		go func(hc healthCheck) {
+			defer wg.Done()
+
+			result := h.runOne(hc)
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[hc.name] = result
+			if result.Status != HealthStatusOk {
+				healthy = false
+			}
+		}(hc)
+	}
+	wg.Wait()
+	return results, healthy
+}
+
+func (h *healthChecker) runOne(hc healthCheck) healthResult {
+	ctx, cancel := stdContext.WithTimeout(stdContext.Background(), h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- hc.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// 检查未处理 ctx 时不再等待
+		err = errHealthCheckTimeout
+	}
+
+	result := healthResult{
+		Status:    HealthStatusOk,
+		Duration:  time.Since(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = HealthStatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func newHealthReport(ctx Context) *healthReport {
+	return &healthReport{
+		Timestamp: time.Now(),
+		Host:      ctx.RequestContext().Request.Host,
+		Status:    HealthStatusOk,
+	}
+}
+
+// livezHandler 进程能处理请求即存活，依赖故障不应导致重启
+func livezHandler() HandlerFunc {
+	return func(ctx Context) {
+		ctx.Payload(newHealthReport(ctx))
+	}
+}
+
+func readyzHandler(checker *healthChecker, draining func() bool) HandlerFunc {
+	return func(ctx Context) {
+		resp := newHealthReport(ctx)
+
+		// 优雅关闭中，让负载均衡摘除流量
+		if draining() {
+			resp.Status = HealthStatusDraining
+			abortUnhealthy(ctx, resp.Status, resp)
+			return
+		}
+
+		var healthy bool
+		resp.Checks, healthy = checker.check()
+		if !healthy {
+			resp.Status = HealthStatusFail
+			abortUnhealthy(ctx, resp.Status, resp)
+			return
+		}
+		ctx.Payload(resp)
+	}
+}
+
+func abortUnhealthy(ctx Context, status string, payload interface{}) {
+	jsonResp := response.NewResponse(payload)
+	jsonResp.Code = response.ServerError
+	jsonResp.Message = status
+	ctx.RequestContext().AbortWithStatusJSON(http.StatusServiceUnavailable, jsonResp)
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	stdContext "context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type healthBody struct {
+	Data healthReport `json:"data"`
+}
+
+func getHealth(t *testing.T, mux Engine, path string) (int, healthReport) {
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/svc/system/"+path, nil))
+
+	var body healthBody
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body.Data
+}
+
+func TestHealthCheck(t *testing.T) {
+	var (
+		calls int32
+		dbErr atomic.Value
+	)
+	dbErr.Store("")
+
+	mux, err := New("svc", zap.NewNop(),
+		WithDisablePProf(), WithDisableSwagger(), WithDisablePrometheus(),
+		WithHealthCheckTimeout(50*time.Millisecond),
+		WithHealthCheckCache(0),
+		WithHealthCheck("db", func(ctx stdContext.Context) error {
+			atomic.AddInt32(&calls, 1)
+			if msg := dbErr.Load().(string); msg != "" {
+				return errors.New(msg)
+			}
+			return nil
+		}),
+		WithHealthCheck("redis", func(ctx stdContext.Context) error {
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, report := getHealth(t, mux, "readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthStatusOk, report.Status)
+	assert.Equal(t, HealthStatusOk, report.Checks["db"].Status)
+	assert.Equal(t, HealthStatusOk, report.Checks["redis"].Status)
+	assert.NotEmpty(t, report.Checks["db"].Duration)
+
+	dbErr.Store("connection refused")
+	code, report = getHealth(t, mux, "readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthStatusFail, report.Status)
+	assert.Equal(t, "connection refused", report.Checks["db"].Error)
+	assert.Equal(t, HealthStatusOk, report.Checks["redis"].Status)
+
+	code, _ = getHealth(t, mux, "health")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	// 依赖故障不影响存活检查
+	before := atomic.LoadInt32(&calls)
+	code, report = getHealth(t, mux, "livez")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthStatusOk, report.Status)
+	assert.Empty(t, report.Checks)
+	assert.Equal(t, before, atomic.LoadInt32(&calls))
+
+	mux.setDraining(true)
+	code, report = getHealth(t, mux, "readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthStatusDraining, report.Status)
+	code, _ = getHealth(t, mux, "livez")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestHealthChecker(t *testing.T) {
+	var calls int32
+	checker := newHealthChecker(&option{
+		healthCheckTimeout: 20 * time.Millisecond,
+		healthChecks: []healthCheck{
+			{name: "slow", check: func(ctx stdContext.Context) error {
+				atomic.AddInt32(&calls, 1)
+				// 不处理 ctx 的检查也会按超时返回
+				time.Sleep(200 * time.Millisecond)
+				return nil
+			}},
+		},
+	})
+
+	start := time.Now()
+	results, healthy := checker.check()
+	assert.Less(t, int64(time.Since(start)), int64(150*time.Millisecond))
+	assert.False(t, healthy)
+	assert.Equal(t, errHealthCheckTimeout.Error(), results["slow"].Error)
+
+	// 缓存时间内不重复执行
+	_, healthy = checker.check()
+	assert.False(t, healthy)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
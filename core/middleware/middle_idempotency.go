@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/cache"
+	"github.com/HYY-yu/seckill.pkg/core"
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	_IdempotencyProcessing = "processing"
+	_IdempotencyDone       = "done"
+
+	_DefaultIdempotencyLockTTL = 30 * time.Second
+	_IdempotencyStoreTimeout   = 3 * time.Second
+)
+
+type IdempotencyOption func(*idempotencyOption)
+
+type idempotencyOption struct {
+	lockTTL time.Duration
+}
+
+// WithIdempotencyLockTTL 处理中记录的过期时间，默认 30s，需大于接口的最长处理时间
+// 进程在处理中退出时，过期后客户端可以重试
+func WithIdempotencyLockTTL(ttl time.Duration) IdempotencyOption {
+	return func(opt *idempotencyOption) {
+		opt.lockTTL = ttl
+	}
+}
+
+// idempotencyRecord 保存在 store 中的请求记录
+type idempotencyRecord struct {
+	State       string                 `json:"state"`
+	Fingerprint string                 `json:"fingerprint"`
+	Status      int                    `json:"status,omitempty"`
+	Response    *response.JsonResponse `json:"response,omitempty"`
+}
+
+// Idempotency 按 Header Idempotency-Key 去重，key 以 UserID 区分，需放在鉴权之后
+// 没有 Idempotency-Key 的请求不处理。
+// 同一个 key：处理中的重复请求返回 409；已完成的请求在 ttl 内重放第一次的返回；方法、路径或请求体不同时返回 409。
+// 返回 5xx 或 panic 时删除记录，允许客户端重试。
+// 处理中的记录使用较短的过期时间（WithIdempotencyLockTTL），完成后替换为 ttl；
+// 保存、删除记录不使用请求的 ctx，客户端断开时记录不会停留在处理中。
+//
+//	g.Handle(http.MethodPost, "/orders", core.Handle(orderSvc.Create), mw.Idempotency(repo, 24*time.Hour))
+func (m *middleware) Idempotency(store cache.Repo, ttl time.Duration, options ...IdempotencyOption) core.HandlerFunc {
+	opt := &idempotencyOption{lockTTL: _DefaultIdempotencyLockTTL}
+	for _, f := range options {
+		f(opt)
+	}
+	keyTemplate := store.KeySchema().Template("idempotency", "user_id", "key")
+
+	return func(c core.Context) {
+		idemKey := c.GetHeader(IdempotencyKeyHeader)
+		if idemKey == "" {
+			return
+		}
+
+		ctx := c.RequestContext().Request.Context()
+		storeKey := keyTemplate.Build(c.UserID(), idemKey)
+		fingerprint := idempotencyFingerprint(c)
+
+		processing, _ := json.Marshal(&idempotencyRecord{State: _IdempotencyProcessing, Fingerprint: fingerprint})
+		ok, err := store.SetNX(ctx, storeKey, string(processing), opt.lockTTL)
+		if err != nil {
+			c.AbortWithError(fmt.Errorf("idempotency lock failed: %w", err))
+			return
+		}
+		if !ok {
+			m.replayIdempotency(c, store, storeKey, fingerprint)
+			return
+		}
+
+		completed := false
+		defer func() {
+			if !completed {
+				storeCtx, cancel := idempotencyStoreContext()
+				defer cancel()
+				store.Del(storeCtx, storeKey)
+			}
+		}()
+
+		c.RequestContext().Next()
+
+		status, body, ok := c.Response()
+		if !ok || status >= http.StatusInternalServerError {
+			return
+		}
+		record := &idempotencyRecord{
+			State:       _IdempotencyDone,
+			Fingerprint: fingerprint,
+			Status:      status,
+		}
+		if body != nil {
+			resp, isJson := body.(*response.JsonResponse)
+			if !isJson {
+				// 非 JsonResponse 无法重放
+				return
+			}
+			record.Response = resp
+		}
+
+		raw, err := json.Marshal(record)
+		if err != nil {
+			m.logger.Error("idempotency marshal response failed", zap.String("key", storeKey), zap.Error(err))
+			return
+		}
+		storeCtx, cancel := idempotencyStoreContext()
+		defer cancel()
+		if err = store.Set(storeCtx, storeKey, string(raw), ttl); err != nil {
+			m.logger.Error("idempotency save response failed", zap.String("key", storeKey), zap.Error(err))
+			return
+		}
+		completed = true
+	}
+}
+
+// idempotencyFingerprint 请求的指纹，同一个 key 用于其他接口时不会重放其他接口的返回
+func idempotencyFingerprint(c core.Context) string {
+	h := sha256.New()
+	req := c.RequestContext().Request
+	h.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	h.Write(c.RequestData())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotencyStoreContext 请求处理完后保存、删除记录使用的 ctx，不受客户端断开影响
+func idempotencyStoreContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), _IdempotencyStoreTimeout)
+}
+
+func (m *middleware) replayIdempotency(c core.Context, store cache.Repo, storeKey, fingerprint string) {
+	raw, err := store.Get(c.RequestContext().Request.Context(), storeKey)
+	if err != nil {
+		// 记录恰好过期或被删除，让客户端重试
+		c.AbortWithError(response.NewErrorAutoMsg(
+			http.StatusConflict,
+			response.RequestInProgress,
+		).WithErr(err))
+		return
+	}
+
+	record := new(idempotencyRecord)
+	if err = json.Unmarshal([]byte(raw), record); err != nil {
+		c.AbortWithError(fmt.Errorf("idempotency unmarshal record failed: %w", err))
+		return
+	}
+
+	switch {
+	case record.Fingerprint != fingerprint:
+		c.AbortWithError(response.NewErrorAutoMsg(
+			http.StatusConflict,
+			response.IdempotencyKeyMismatch,
+		))
+	case record.State != _IdempotencyDone:
+		c.AbortWithError(response.NewErrorAutoMsg(
+			http.StatusConflict,
+			response.RequestInProgress,
+		))
+	default:
+		c.SetHeader("Idempotent-Replayed", "true")
+		if record.Response == nil {
+			c.Replay(record.Status, nil)
+			return
+		}
+		c.Replay(record.Status, record.Response)
+	}
+}
@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/cache"
+	"github.com/HYY-yu/seckill.pkg/core"
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+func TestIdempotency(t *testing.T) {
+	mux, err := core.New("idem_test", zap.NewNop(), core.WithDisablePProf(), core.WithDisableSwagger(), core.WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := cache.NewMemoryRepo("sk")
+	mw := New(zap.NewNop(), "secret")
+
+	var created, failed int32
+	block := make(chan struct{})
+	g := mux.Group("/api")
+	g.Use(core.WrapAuthHandler(func(ctx core.Context) (int64, string, response.Error) {
+		return cast.ToInt64(ctx.GetHeader("X-User")), "", nil
+	}))
+	g.POST("/orders", mw.Idempotency(store, time.Minute), func(ctx core.Context) {
+		if ctx.GetHeader("X-Block") != "" {
+			<-block
+		}
+		n := atomic.AddInt32(&created, 1)
+		ctx.PayloadWithCode(http.StatusCreated, map[string]int32{"order_id": n})
+	})
+	g.POST("/carts", mw.Idempotency(store, time.Minute), func(ctx core.Context) {
+		ctx.PayloadWithCode(http.StatusCreated, "added")
+	})
+	g.POST("/flaky", mw.Idempotency(store, time.Minute), func(ctx core.Context) {
+		if atomic.AddInt32(&failed, 1) == 1 {
+			ctx.AbortWithError(errors.New("db down"))
+			return
+		}
+		ctx.Payload(nil)
+	})
+
+	do := func(path, user, key, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/idem_test/api"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User", user)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) *response.JsonResponse {
+		resp := new(response.JsonResponse)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		return resp
+	}
+
+	t.Run("Replay", func(t *testing.T) {
+		first := do("/orders", "1", "k1", `{"sku":1}`)
+		assert.Equal(t, http.StatusCreated, first.Code)
+
+		second := do("/orders", "1", "k1", `{"sku":1}`)
+		assert.Equal(t, http.StatusCreated, second.Code)
+		assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, int32(1), atomic.LoadInt32(&created))
+
+		// 不同用户、没有 key 的请求不受影响
+		assert.Equal(t, http.StatusCreated, do("/orders", "2", "k1", `{"sku":1}`).Code)
+		assert.Equal(t, http.StatusCreated, do("/orders", "1", "", `{"sku":1}`).Code)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&created))
+	})
+
+	t.Run("BodyMismatch", func(t *testing.T) {
+		w := do("/orders", "1", "k1", `{"sku":2}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, response.IdempotencyKeyMismatch, decode(w).Code)
+	})
+
+	t.Run("OtherEndpoint", func(t *testing.T) {
+		// 相同 key、相同请求体用于其他接口时不重放
+		w := do("/carts", "1", "k1", `{"sku":1}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, response.IdempotencyKeyMismatch, decode(w).Code)
+	})
+
+	t.Run("InFlight", func(t *testing.T) {
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			done <- do("/orders", "1", "k2", `{}`, "X-Block", "1")
+		}()
+		// 等待第一个请求拿到锁
+		assert.Eventually(t, func() bool {
+			return store.Exists(context.Background(), store.KeySchema().Template("idempotency", "user_id", "key").Build(1, "k2"))
+		}, time.Second, 5*time.Millisecond)
+
+		w := do("/orders", "1", "k2", `{}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, response.RequestInProgress, decode(w).Code)
+
+		close(block)
+		assert.Equal(t, http.StatusCreated, (<-done).Code)
+		assert.Equal(t, http.StatusCreated, do("/orders", "1", "k2", `{}`).Code)
+	})
+
+	t.Run("RetryAfterServerError", func(t *testing.T) {
+		assert.Equal(t, http.StatusInternalServerError, do("/flaky", "1", "k3", `{}`).Code)
+		assert.Equal(t, http.StatusOK, do("/flaky", "1", "k3", `{}`).Code)
+		assert.Equal(t, http.StatusOK, do("/flaky", "1", "k3", `{}`).Code)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&failed))
+	})
+}
+
+// ctxRepo 与 redis 一致，ctx 取消后写入失败
+type ctxRepo struct {
+	cache.Repo
+}
+
+func (r ctxRepo) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.Repo.Set(ctx, key, value, ttl)
+}
+
+func (r ctxRepo) Del(ctx context.Context, key string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return r.Repo.Del(ctx, key)
+}
+
+func TestIdempotency_ClientCanceled(t *testing.T) {
+	mux, err := core.New("idem_cancel_test", zap.NewNop(), core.WithDisablePProf(), core.WithDisableSwagger(), core.WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := ctxRepo{Repo: cache.NewMemoryRepo("sk")}
+	storeKey := store.KeySchema().Template("idempotency", "user_id", "key").Build(0, "k1")
+	mw := New(zap.NewNop(), "secret")
+
+	var cancel context.CancelFunc
+	var lockTTL time.Duration
+	mux.Group("/api").POST("/orders", mw.Idempotency(store, time.Minute, WithIdempotencyLockTTL(time.Second)), func(ctx core.Context) {
+		lockTTL, _ = store.TTL(context.Background(), storeKey)
+		// 客户端在处理中断开
+		cancel()
+		if ctx.GetHeader("X-Fail") != "" {
+			ctx.AbortWithError(errors.New("db down"))
+			return
+		}
+		ctx.PayloadWithCode(http.StatusCreated, "created")
+	})
+
+	do := func(header ...string) *httptest.ResponseRecorder {
+		var reqCtx context.Context
+		reqCtx, cancel = context.WithCancel(context.Background())
+		req := httptest.NewRequest(http.MethodPost, "/idem_cancel_test/api/orders", strings.NewReader(`{}`)).WithContext(reqCtx)
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	// 处理失败时删除记录，允许重试
+	assert.Equal(t, http.StatusInternalServerError, do("X-Fail", "1").Code)
+	assert.False(t, store.Exists(context.Background(), storeKey))
+
+	// 处理中使用 lock ttl，完成后替换为 ttl
+	assert.Equal(t, http.StatusCreated, do().Code)
+	assert.True(t, lockTTL > 0 && lockTTL <= time.Second, lockTTL)
+	ttl, err := store.TTL(context.Background(), storeKey)
+	assert.NoError(t, err)
+	assert.True(t, ttl > time.Second, ttl)
+
+	w := do()
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotency_ReplaySpanStatus(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(provider)
+
+	mux, err := core.New("idem_span_test", zap.NewNop(), core.WithDisablePProf(), core.WithDisableSwagger(), core.WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw := New(zap.NewNop(), "secret")
+	mux.Group("/api").POST("/orders", mw.Idempotency(cache.NewMemoryRepo("sk"), time.Minute), func(ctx core.Context) {
+		ctx.PayloadWithCode(http.StatusCreated, "created")
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/idem_span_test/api/orders", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	// 重放的成功返回不记为错误
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		for _, span := range spans {
+			assert.Equal(t, codes.Ok, span.Status().Code)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/spf13/cast"
+
+	"github.com/HYY-yu/seckill.pkg/cache"
+	"github.com/HYY-yu/seckill.pkg/core"
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+	"github.com/HYY-yu/seckill.pkg/pkg/token"
+)
+
+// 签名相关的 Header
+const (
+	SignAppKeyHeader    = "X-App-Key"
+	SignTimestampHeader = "X-Timestamp"
+	SignNonceHeader     = "X-Nonce"
+	SignatureHeader     = "X-Signature"
+	SignMethodHeader    = "X-Sign-Method"
+)
+
+// 签名算法，X-Sign-Method 为空时使用 md5
+const (
+	SignMethodMD5        = "md5"
+	SignMethodHmacSHA256 = "hmac-sha256"
+)
+
+// 参与签名的附加参数名
+const (
+	SignNonceParam = "nonce"
+	// SignBodyParam 非表单请求体（如 JSON）的 sha256 十六进制小写
+	SignBodyParam = "body_sha256"
+)
+
+const _DefaultSignWindow = 5 * time.Minute
+
+// SecretLookup 根据 app key 查询签名密钥，不存在时 ok 为 false
+type SecretLookup func(appKey string) (secret string, ok bool)
+
+type SignOption func(*signOption)
+
+type signOption struct {
+	window time.Duration
+}
+
+// WithSignWindow X-Timestamp 与服务器时间允许的误差，默认 5 分钟
+func WithSignWindow(window time.Duration) SignOption {
+	return func(opt *signOption) {
+		opt.window = window
+	}
+}
+
+// SignatureVerify 校验 token.UrlSign、token.UrlSignHmac 生成的签名，防参数篡改，防重放攻击
+// 客户端签名时 params 为 querystring、x-www-form-urlencoded 表单参数再加上 nonce=X-Nonce，
+// 其它请求体（如 JSON）不为空时再加上 body_sha256=hex(sha256(body))，
+// path 为不带 querystring 的请求路径，timestamp 为 X-Timestamp（Unix 秒），密钥为 X-App-Key 对应的 secret。
+// X-Timestamp 超出时间窗口或 X-Nonce 在窗口内重复使用时返回 SignatureExpired。
+//
+//	g.Use(mw.SignatureVerify(func(appKey string) (string, bool) {
+//		secret, ok := appSecrets[appKey]
+//		return secret, ok
+//	}, repo))
+func (m *middleware) SignatureVerify(secretLookup SecretLookup, store cache.Repo, options ...SignOption) core.HandlerFunc {
+	opt := &signOption{window: _DefaultSignWindow}
+	for _, f := range options {
+		f(opt)
+	}
+	nonceKey := store.KeySchema().Template("sign_nonce", "app_key", "nonce")
+
+	return func(c core.Context) {
+		appKey := c.GetHeader(SignAppKeyHeader)
+		nonce := c.GetHeader(SignNonceHeader)
+		signature := c.GetHeader(SignatureHeader)
+		timestamp, err := cast.ToInt64E(c.GetHeader(SignTimestampHeader))
+		if appKey == "" || nonce == "" || signature == "" || err != nil {
+			c.AbortWithError(signatureError(errors.New("Header 中缺少签名参数 ")))
+			return
+		}
+
+		secret, ok := secretLookup(appKey)
+		if !ok {
+			// 与签名错误相同，不暴露 app key 是否存在
+			c.AbortWithError(signatureError(errSignMismatch))
+			return
+		}
+
+		if diff := time.Since(time.Unix(timestamp, 0)); diff > opt.window || diff < -opt.window {
+			c.AbortWithError(response.NewErrorAutoMsg(
+				http.StatusUnauthorized,
+				response.SignatureExpired,
+			))
+			return
+		}
+
+		req := c.RequestContext().Request
+		params, err := signParams(c)
+		if err != nil {
+			c.AbortWithError(signatureError(err))
+			return
+		}
+		params.Set(SignNonceParam, nonce)
+
+		var expect string
+		switch method := strings.ToLower(c.GetHeader(SignMethodHeader)); method {
+		case "", SignMethodMD5:
+			expect, err = token.New(secret).UrlSign(timestamp, req.URL.Path, req.Method, params)
+		case SignMethodHmacSHA256:
+			expect, err = token.New(secret).UrlSignHmac(timestamp, req.URL.Path, req.Method, params)
+		default:
+			err = fmt.Errorf("不支持的签名算法 %s ", method)
+		}
+		if err != nil {
+			c.AbortWithError(signatureError(err))
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(expect), []byte(strings.ToLower(signature))) != 1 {
+			c.AbortWithError(signatureError(errSignMismatch))
+			return
+		}
+
+		// 签名正确后才记录 nonce，时间窗口两侧都可能被重放，保留 2 倍窗口
+		ok, err = store.SetNX(req.Context(), nonceKey.Build(appKey, nonce), "1", 2*opt.window)
+		if err != nil {
+			c.AbortWithError(fmt.Errorf("signature save nonce failed: %w", err))
+			return
+		}
+		if !ok {
+			c.AbortWithError(response.NewErrorAutoMsg(
+				http.StatusUnauthorized,
+				response.SignatureExpired,
+			))
+			return
+		}
+	}
+}
+
+var errSignMismatch = errors.New("签名不一致 ")
+
+// signParams querystring 与 x-www-form-urlencoded 表单参数，其它请求体加上 body_sha256，不消耗请求体
+func signParams(c core.Context) (url.Values, error) {
+	req := c.RequestContext().Request
+	params := req.URL.Query()
+	if req.Method == http.MethodGet {
+		return params, nil
+	}
+
+	body := c.RequestData()
+	if c.RequestContext().ContentType() != binding.MIMEPOSTForm {
+		if len(body) > 0 {
+			sum := sha256.Sum256(body)
+			params.Set(SignBodyParam, hex.EncodeToString(sum[:]))
+		}
+		return params, nil
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("parse form failed: %w", err)
+	}
+	for k, v := range form {
+		params[k] = append(params[k], v...)
+	}
+	return params, nil
+}
+
+func signatureError(err error) response.Error {
+	return response.NewErrorAutoMsg(
+		http.StatusUnauthorized,
+		response.SignatureError,
+	).WithErr(err)
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/cache"
+	"github.com/HYY-yu/seckill.pkg/core"
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+	"github.com/HYY-yu/seckill.pkg/pkg/token"
+)
+
+func TestSignatureVerify(t *testing.T) {
+	mux, err := core.New("sign_test", zap.NewNop(), core.WithDisablePProf(), core.WithDisableSwagger(), core.WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secrets := map[string]string{"app1": "secret1"}
+	mw := New(zap.NewNop(), "secret")
+	g := mux.Group("/open")
+	g.Use(mw.SignatureVerify(func(appKey string) (string, bool) {
+		secret, ok := secrets[appKey]
+		return secret, ok
+	}, cache.NewMemoryRepo("sk"), WithSignWindow(time.Minute)))
+	g.POST("/pay", func(ctx core.Context) {
+		var req struct {
+			Amount int `json:"amount"`
+		}
+		_ = ctx.ShouldBindJSON(&req)
+		ctx.Payload(req.Amount)
+	})
+	g.POST("/orders", func(ctx core.Context) {
+		var req struct {
+			Sku string `form:"sku"`
+		}
+		_ = ctx.ShouldBindForm(&req)
+		ctx.Payload(req.Sku)
+	})
+
+	type signReq struct {
+		appKey, nonce, method, query, form string
+		timestamp                          int64
+		tamper                             string
+	}
+	do := func(r signReq) *httptest.ResponseRecorder {
+		params, _ := url.ParseQuery(r.query)
+		form, _ := url.ParseQuery(r.form)
+		for k, v := range form {
+			params[k] = append(params[k], v...)
+		}
+		params.Set("nonce", r.nonce)
+
+		tk := token.New(secrets["app1"])
+		sign, _ := tk.UrlSign(r.timestamp, "/sign_test/open/orders", http.MethodPost, params)
+		if r.method == SignMethodHmacSHA256 {
+			sign, _ = tk.UrlSignHmac(r.timestamp, "/sign_test/open/orders", http.MethodPost, params)
+		}
+
+		body := r.form
+		if r.tamper != "" {
+			body = r.tamper
+		}
+		req := httptest.NewRequest(http.MethodPost, "/sign_test/open/orders?"+r.query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set(SignAppKeyHeader, r.appKey)
+		req.Header.Set(SignTimestampHeader, strconv.FormatInt(r.timestamp, 10))
+		req.Header.Set(SignNonceHeader, r.nonce)
+		req.Header.Set(SignatureHeader, sign)
+		req.Header.Set(SignMethodHeader, r.method)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) *response.JsonResponse {
+		resp := new(response.JsonResponse)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		return resp
+	}
+	now := time.Now().Unix()
+
+	// md5 与 hmac-sha256 都可以通过，表单参数仍可绑定
+	for _, method := range []string{"", SignMethodMD5, SignMethodHmacSHA256} {
+		w := do(signReq{appKey: "app1", nonce: "n-" + method, method: method, query: "a=1", form: "sku=s1", timestamp: now})
+		assert.Equal(t, http.StatusOK, w.Code, method)
+		assert.Equal(t, "s1", decode(w).Data)
+	}
+
+	// 重放
+	w := do(signReq{appKey: "app1", nonce: "n-" + SignMethodMD5, method: SignMethodMD5, query: "a=1", form: "sku=s1", timestamp: now})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, response.SignatureExpired, decode(w).Code)
+
+	// 时间戳过期
+	w = do(signReq{appKey: "app1", nonce: "n1", form: "sku=s1", timestamp: now - 120})
+	assert.Equal(t, response.SignatureExpired, decode(w).Code)
+
+	// 参数被篡改
+	w = do(signReq{appKey: "app1", nonce: "n2", form: "sku=s1", tamper: "sku=s2", timestamp: now})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, response.SignatureError, decode(w).Code)
+
+	// 未知的 app key、签名算法，返回中不包含 app key
+	w = do(signReq{appKey: "app2", nonce: "n3", timestamp: now})
+	assert.Equal(t, response.SignatureError, decode(w).Code)
+	assert.NotContains(t, w.Body.String(), "app2")
+	w = do(signReq{appKey: "app1", nonce: "n4", method: "sha1", timestamp: now})
+	assert.Equal(t, response.SignatureError, decode(w).Code)
+
+	// 签名失败的请求不占用 nonce
+	w = do(signReq{appKey: "app1", nonce: "n2", form: "sku=s1", timestamp: now})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// JSON 请求体以 body_sha256 参与签名
+	doJSON := func(nonce, signed, sent string) *httptest.ResponseRecorder {
+		params := url.Values{}
+		params.Set(SignNonceParam, nonce)
+		sum := sha256.Sum256([]byte(signed))
+		params.Set(SignBodyParam, hex.EncodeToString(sum[:]))
+		sign, _ := token.New(secrets["app1"]).UrlSignHmac(now, "/sign_test/open/pay", http.MethodPost, params)
+
+		req := httptest.NewRequest(http.MethodPost, "/sign_test/open/pay", strings.NewReader(sent))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(SignAppKeyHeader, "app1")
+		req.Header.Set(SignTimestampHeader, strconv.FormatInt(now, 10))
+		req.Header.Set(SignNonceHeader, nonce)
+		req.Header.Set(SignatureHeader, sign)
+		req.Header.Set(SignMethodHeader, SignMethodHmacSHA256)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	w = doJSON("j1", `{"amount":100}`, `{"amount":100}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, 100, decode(w).Data)
+
+	w = doJSON("j2", `{"amount":100}`, `{"amount":1}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, response.SignatureError, decode(w).Code)
+}
@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"time"
+
+	"github.com/HYY-yu/seckill.pkg/cache"
 	"github.com/HYY-yu/seckill.pkg/core"
 	"github.com/juju/ratelimit"
 	"go.uber.org/zap"
@@ -18,6 +21,12 @@ type Middleware interface {
 	DisableLog() core.HandlerFunc
 
 	RequestLimit() core.HandlerFunc
+
+	// Idempotency 按 Header Idempotency-Key 去重，重复请求重放第一次的返回
+	Idempotency(store cache.Repo, ttl time.Duration, options ...IdempotencyOption) core.HandlerFunc
+
+	// SignatureVerify 校验 token.UrlSign、token.UrlSignHmac 生成的签名，nonce 记录在 store 中防重放
+	SignatureVerify(secretLookup SecretLookup, store cache.Repo, options ...SignOption) core.HandlerFunc
 }
 
 type middleware struct {
@@ -0,0 +1,420 @@
+package core
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/util/gvalid"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+// OpenAPI 文档
+// 由 RouterGroup 注册的路由生成，请求与返回类型来自 RouterGroup.Handle 注册的 Endpoint，鉴权来自 RouterGroup.UseAuth，
+// 挂载在 /<serverName>/openapi.json，swagger UI 使用此文档。
+//
+// 请求参数：uri tag 为 path 参数，form tag 为 query 参数，json tag 为 application/json 请求体，
+// v tag 包含 required 时为必填。返回统一包装在 response.JsonResponse 的 data 中。
+// 其它方式注册的路由只记录方法与路径。
+
+const (
+	_OpenAPIVersion = "3.0.3"
+	_SecurityBearer = "bearerAuth"
+)
+
+// routeInfo 注册的路由
+type routeInfo struct {
+	method string
+	path   string // gin 格式的完整路径，如 /svc/api/users/:id
+	meta   *HandlerMeta
+	auth   bool
+}
+
+type routeRegistry struct {
+	mu     sync.RWMutex
+	routes []routeInfo
+}
+
+func (r *routeRegistry) add(route routeInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, route)
+}
+
+func (r *routeRegistry) list() []routeInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	routes := make([]routeInfo, len(r.routes))
+	copy(routes, r.routes)
+	return routes
+}
+
+type openAPI struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       openAPIInfo                     `json:"info"`
+	Servers    []openAPIServer                 `json:"servers"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components openAPIComponents               `json:"components"`
+	// ErrorCodes 全部业务码及说明
+	ErrorCodes map[string]string `json:"x-error-codes,omitempty"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes,omitempty"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]apiResp    `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type apiResp struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+}
+
+func openAPIHandler(title, basePath string, routes *routeRegistry) HandlerFunc {
+	return func(ctx Context) {
+		// 路由可能在 New 之后注册，每次请求时生成
+		doc := buildOpenAPI(title, basePath, routes.list())
+		ctx.RequestContext().JSON(http.StatusOK, doc)
+	}
+}
+
+func buildOpenAPI(title, basePath string, routes []routeInfo) *openAPI {
+	g := &schemaGenerator{schemas: make(map[string]*schema)}
+	doc := &openAPI{
+		OpenAPI: _OpenAPIVersion,
+		Info:    openAPIInfo{Title: title, Version: "1.0.0"},
+		Servers: []openAPIServer{{URL: basePath}},
+		Paths:   make(map[string]map[string]operation),
+		Components: openAPIComponents{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]securityScheme{
+				_SecurityBearer: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		ErrorCodes: make(map[string]string),
+	}
+	for code, text := range response.Codes() {
+		doc.ErrorCodes[strconv.Itoa(code)] = text
+	}
+
+	for _, route := range routes {
+		path := openAPIPath(strings.TrimPrefix(route.path, basePath))
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]operation)
+		}
+		doc.Paths[path][strings.ToLower(route.method)] = g.operation(route)
+	}
+	return doc
+}
+
+// openAPIPath /users/:id/*file -> /users/{id}/{file}
+func openAPIPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+type schemaGenerator struct {
+	schemas map[string]*schema
+}
+
+func (g *schemaGenerator) operation(route routeInfo) operation {
+	op := operation{
+		Responses: make(map[string]apiResp),
+	}
+	meta := route.meta
+	if meta == nil {
+		meta = new(HandlerMeta)
+	}
+	op.Summary = meta.Summary
+	op.Tags = meta.Tags
+
+	var data *schema
+	if meta.Response != nil {
+		data = g.schemaOf(meta.Response)
+	}
+	op.Responses[strconv.Itoa(http.StatusOK)] = apiResp{
+		Description: "OK",
+		Content:     jsonContent(envelope(data)),
+	}
+
+	if meta.Request != nil {
+		op.Parameters, op.RequestBody = g.request(route.method, meta.Request)
+		addErrorResponse(op.Responses, http.StatusBadRequest, response.ParamBindError)
+	}
+	if route.auth {
+		op.Security = []map[string][]string{{_SecurityBearer: {}}}
+		addErrorResponse(op.Responses, http.StatusUnauthorized, response.AuthorizationError)
+	}
+	for _, e := range meta.Errors {
+		addErrorResponse(op.Responses, e.GetHttpCode(), e.GetBusinessCode())
+	}
+	addErrorResponse(op.Responses, http.StatusInternalServerError, response.ServerError)
+	return op
+}
+
+// addErrorResponse 同一 HTTP 状态码的多个业务码合并到描述中
+func addErrorResponse(responses map[string]apiResp, httpCode, businessCode int) {
+	if httpCode == 0 {
+		httpCode = http.StatusInternalServerError
+	}
+	key := strconv.Itoa(httpCode)
+	desc := strconv.Itoa(businessCode) + ": " + response.Text(businessCode)
+
+	if resp, ok := responses[key]; ok {
+		if !strings.Contains(resp.Description, desc) {
+			resp.Description += "; " + desc
+			responses[key] = resp
+		}
+		return
+	}
+	responses[key] = apiResp{
+		Description: desc,
+		Content:     jsonContent(envelope(nil)),
+	}
+}
+
+func jsonContent(s *schema) map[string]mediaType {
+	return map[string]mediaType{"application/json": {Schema: s}}
+}
+
+// envelope response.JsonResponse
+func envelope(data *schema) *schema {
+	if data == nil {
+		data = &schema{Type: "object"}
+	}
+	return &schema{
+		Type: "object",
+		Properties: map[string]*schema{
+			"code":    {Type: "integer", Description: "业务码"},
+			"message": {Type: "string", Description: "描述信息"},
+			"data":    data,
+		},
+	}
+}
+
+func (g *schemaGenerator) request(method string, t reflect.Type) ([]parameter, *requestBody) {
+	var (
+		params []parameter
+		body   = &schema{Type: "object", Properties: make(map[string]*schema)}
+	)
+	// GET、HEAD、DELETE 没有请求体，同时有 form、json tag 的字段按 query 参数处理，其他方法按 JSON 请求体处理
+	hasBody := method != http.MethodGet && method != http.MethodHead && method != http.MethodDelete
+	for _, field := range structFields(t) {
+		required := isRequired(field)
+		if name := tagName(field, "uri"); name != "" {
+			params = append(params, parameter{Name: name, In: "path", Required: true, Schema: g.schemaOf(field.Type)})
+			continue
+		}
+		name := tagName(field, "json")
+		if form := tagName(field, "form"); form != "" && (!hasBody || name == "") {
+			params = append(params, parameter{Name: form, In: "query", Required: required, Schema: g.schemaOf(field.Type)})
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		body.Properties[name] = g.schemaOf(field.Type)
+		if required {
+			body.Required = append(body.Required, name)
+		}
+	}
+
+	if len(body.Properties) == 0 || !hasBody {
+		return params, nil
+	}
+	return params, &requestBody{
+		Required: len(body.Required) > 0,
+		Content:  jsonContent(body),
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) *schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &schema{Type: "string", Format: "byte"}
+		}
+		return &schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &schema{Type: "string", Format: "date-time"}
+		}
+		return g.structSchema(t)
+	}
+	// interface{} 等任意类型
+	return &schema{}
+}
+
+// structSchema 具名结构体放到 components 中引用
+func (g *schemaGenerator) structSchema(t reflect.Type) *schema {
+	name := schemaName(t)
+	if name != "" {
+		if _, ok := g.schemas[name]; ok {
+			return &schema{Ref: "#/components/schemas/" + name}
+		}
+		// 先占位，避免递归类型死循环
+		g.schemas[name] = &schema{}
+	}
+
+	s := &schema{Type: "object", Properties: make(map[string]*schema)}
+	for _, field := range structFields(t) {
+		fieldName := tagName(field, "json")
+		if fieldName == "" {
+			fieldName = field.Name
+		}
+		s.Properties[fieldName] = g.schemaOf(field.Type)
+		if isRequired(field) {
+			s.Required = append(s.Required, fieldName)
+		}
+	}
+	sort.Strings(s.Required)
+
+	if name == "" {
+		return s
+	}
+	*g.schemas[name] = *s
+	return &schema{Ref: "#/components/schemas/" + name}
+}
+
+func schemaName(t reflect.Type) string {
+	if t.Name() == "" {
+		return ""
+	}
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if pkg == "" {
+		return t.Name()
+	}
+	return pkg + "." + t.Name()
+}
+
+// structFields 导出字段，展开匿名嵌入的结构体
+func structFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && tagName(field, "json") == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, structFields(ft)...)
+				continue
+			}
+		}
+		if field.PkgPath != "" || field.Tag.Get("json") == "-" {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func tagName(field reflect.StructField, key string) string {
+	name := strings.Split(field.Tag.Get(key), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func isRequired(field reflect.StructField) bool {
+	tag := validationTag(field)
+	if tag == "" {
+		return false
+	}
+	_, rules, _ := gvalid.ParseTagValue(tag)
+	for _, rule := range strings.Split(rules, "|") {
+		if strings.TrimSpace(rule) == "required" {
+			return true
+		}
+	}
+	return false
+}
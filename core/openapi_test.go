@@ -0,0 +1,165 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+type listOrderReq struct {
+	ShopID int64  `uri:"shop_id"`
+	Status int    `form:"status" v:"required"`
+	Remark string `json:"remark"`
+}
+
+type orderItem struct {
+	SkuCode string `json:"sku_code"`
+	Count   int    `json:"count"`
+}
+
+type orderResp struct {
+	ID    int64        `json:"id"`
+	Items []*orderItem `json:"items"`
+	Next  *orderResp   `json:"next"`
+}
+
+type createOrderReq struct {
+	SkuCode string `json:"sku_code" v:"required"`
+	Count   int    `json:"count"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	mux, err := New("svc", zap.NewNop(), WithDisablePProf(), WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errSoldOut := response.NewErrorAutoMsg(http.StatusConflict, response.DBDuplicateKey)
+	g := mux.Group("/api")
+	g.GET("/ping", func(ctx Context) {})
+	g.UseAuth(func(ctx Context) (int64, string, response.Error) {
+		return 1, "alice", nil
+	})
+	g.Handle(http.MethodGet, "/shops/:shop_id/orders", Handle(func(svc SvcContext, req *listOrderReq) ([]*orderResp, error) {
+		return nil, nil
+	}, WithSummary("订单列表"), WithTags("order")))
+	g.Handle(http.MethodPost, "/orders", Handle(func(svc SvcContext, req *createOrderReq) (*orderResp, error) {
+		return nil, nil
+	}, WithErrors(errSoldOut)), func(ctx Context) {
+		ctx.RequestContext().Next()
+	})
+	// 作为普通 HandlerFunc 注册时不记录类型
+	g.PUT("/orders", Handle(func(svc SvcContext, req *createOrderReq) error {
+		return nil
+	}).HandlerFunc())
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/svc/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc map[string]interface{}
+	if !assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc)) {
+		return
+	}
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	assert.Equal(t, "/svc", doc["servers"].([]interface{})[0].(map[string]interface{})["url"])
+	assert.Equal(t, response.Text(response.ParamBindError), doc["x-error-codes"].(map[string]interface{})["10004"])
+
+	paths := doc["paths"].(map[string]interface{})
+	assert.Contains(t, paths, "/system/readyz")
+
+	ping := paths["/api/ping"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Nil(t, ping["security"])
+
+	list := paths["/api/shops/{shop_id}/orders"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal(t, "订单列表", list["summary"])
+	assert.NotNil(t, list["security"])
+	assert.Nil(t, list["requestBody"])
+	params := list["parameters"].([]interface{})
+	if assert.Len(t, params, 2) {
+		assert.Equal(t, map[string]interface{}{
+			"name": "shop_id", "in": "path", "required": true,
+			"schema": map[string]interface{}{"type": "integer", "format": "int64"},
+		}, params[0])
+		assert.Equal(t, "query", params[1].(map[string]interface{})["in"])
+		assert.Equal(t, true, params[1].(map[string]interface{})["required"])
+	}
+	responses := list["responses"].(map[string]interface{})
+	assert.Contains(t, responses, "400")
+	assert.Contains(t, responses, "401")
+	assert.Contains(t, responses, "500")
+
+	create := paths["/api/orders"].(map[string]interface{})["post"].(map[string]interface{})
+	body := create["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"sku_code"}, body["required"])
+	assert.Equal(t, "10006: 数据已存在", create["responses"].(map[string]interface{})["409"].(map[string]interface{})["description"])
+
+	update := paths["/api/orders"].(map[string]interface{})["put"].(map[string]interface{})
+	assert.Nil(t, update["requestBody"])
+	assert.NotNil(t, update["security"])
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	order := schemas["core.orderResp"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(t, "#/components/schemas/core.orderResp", order["next"].(map[string]interface{})["$ref"])
+	assert.Equal(t, "#/components/schemas/core.orderItem", order["items"].(map[string]interface{})["items"].(map[string]interface{})["$ref"])
+}
+
+type searchOrderReq struct {
+	Keyword string `form:"keyword" json:"keyword" v:"required"`
+	Page    int    `form:"page"`
+}
+
+func TestOpenAPI_FormAndJSON(t *testing.T) {
+	mux, err := New("svc", zap.NewNop(), WithDisablePProf(), WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := Handle(func(svc SvcContext, req *searchOrderReq) error {
+		return nil
+	})
+	g := mux.Group("/api")
+	g.Handle(http.MethodGet, "/orders/search", handler)
+	g.Handle(http.MethodPost, "/orders/search", handler)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/svc/openapi.json", nil))
+	var doc map[string]interface{}
+	if !assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc)) {
+		return
+	}
+	path := doc["paths"].(map[string]interface{})["/api/orders/search"].(map[string]interface{})
+
+	names := func(op map[string]interface{}) []string {
+		var result []string
+		params, _ := op["parameters"].([]interface{})
+		for _, p := range params {
+			assert.Equal(t, "query", p.(map[string]interface{})["in"])
+			result = append(result, p.(map[string]interface{})["name"].(string))
+		}
+		return result
+	}
+
+	// GET 全部为 query 参数
+	get := path["get"].(map[string]interface{})
+	assert.Nil(t, get["requestBody"])
+	assert.Equal(t, []string{"keyword", "page"}, names(get))
+
+	// POST 有 json tag 的字段在请求体中，只有 form tag 的字段仍为 query 参数
+	post := path["post"].(map[string]interface{})
+	assert.Equal(t, []string{"page"}, names(post))
+	body := post["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+	assert.Contains(t, body["properties"], "keyword")
+	assert.Equal(t, []interface{}{"keyword"}, body["required"])
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	assert.Equal(t, "/users/{id}/files/{path}", openAPIPath("/users/:id/files/*path"))
+	assert.Equal(t, "/", openAPIPath(""))
+}
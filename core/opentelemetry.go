@@ -10,8 +10,6 @@ import (
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
-
-	"github.com/HYY-yu/seckill.pkg/pkg/response"
 )
 
 // RecordMetrics 记录prometheus指标用
@@ -56,18 +54,17 @@ func (o *OpenTelemetry) Telemetry(c Context, serverName string) {
 	}
 
 	// 获取返回信息
-	resp := c.getResponse()
-	if resp == nil {
+	meta := c.getResponseMeta()
+	if meta == nil {
 		return
 	}
-	jsonResp := resp.(*response.JsonResponse)
 
 	decodedURL := c.URI()
 	telemetry := &RequestTelemetry{
 		Method:       ctx.Request.Method,
 		Path:         decodedURL,
 		HttpCode:     ctx.Writer.Status(),
-		BusinessCode: jsonResp.Code,
+		BusinessCode: meta.businessCode,
 		CostSeconds:  time.Since(ts).Seconds(),
 	}
 
@@ -79,10 +76,11 @@ func (o *OpenTelemetry) Telemetry(c Context, serverName string) {
 		attribute.Int("http.business_code", telemetry.BusinessCode),
 		attribute.Float64("http.cost_seconds", telemetry.CostSeconds),
 	)
-	if !ctx.IsAborted() && ctx.Writer.Status() == http.StatusOK {
+	// Replay 会 Abort 后续的 Handler，但重放的成功返回不算错误
+	if (!ctx.IsAborted() || meta.replayed) && ctx.Writer.Status() < http.StatusBadRequest {
 		span.SetStatus(codes.Ok, "")
 	} else {
-		span.SetStatus(codes.Error, jsonResp.Message)
+		span.SetStatus(codes.Error, meta.message)
 	}
 
 	// Metrics
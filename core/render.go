@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"sort"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+// ResponseRenderer 构造返回的 body，默认为 response.JsonResponse
+//
+//	core.New("seckill", logger, core.WithResponseRenderer(myRenderer{}))
+//
+// body 按请求的 Accept 编码为 JSON、XML、msgpack 或 protobuf，
+// XML 中 map[string]interface{} 的 key 作为子元素（如 payload 为 nil 时的 Data），编码失败时返回 406；
+// protobuf 只支持 body 或 payload 为 proto.Message，否则使用 JSON。
+type ResponseRenderer interface {
+	// Success 正确返回的 body
+	Success(payload interface{}) interface{}
+	// Error 错误返回的 body
+	Error(err response.Error) interface{}
+}
+
+// WithResponseRenderer 设置返回的 body 结构
+func WithResponseRenderer(renderer ResponseRenderer) Option {
+	return func(opt *option) {
+		opt.renderer = renderer
+	}
+}
+
+var _ ResponseRenderer = (*jsonResponseRenderer)(nil)
+
+type jsonResponseRenderer struct{}
+
+func (jsonResponseRenderer) Success(payload interface{}) interface{} {
+	return response.NewResponse(payload)
+}
+
+func (jsonResponseRenderer) Error(err response.Error) interface{} {
+	resp := response.NewResponse(err.GetData())
+	resp.Code = err.GetBusinessCode()
+	resp.Message = err.GetMsg()
+	return resp
+}
+
+// responseMeta 返回的摘要，Telemetry 等不依赖具体的 body 结构
+type responseMeta struct {
+	httpCode     int
+	businessCode int
+	message      string
+	// replayed 由 Replay 返回，请求被 Abort 但并非失败
+	replayed bool
+}
+
+var _Offers = []string{
+	binding.MIMEJSON,
+	binding.MIMEXML,
+	binding.MIMEXML2,
+	binding.MIMEMSGPACK,
+	binding.MIMEMSGPACK2,
+	binding.MIMEPROTOBUF,
+}
+
+// errUseJSON Accept 为 JSON 或者不支持的格式，使用 JSON
+var errUseJSON = errors.New("use json")
+
+// encode 按 Accept 编码，返回 errUseJSON 时使用 JSON
+func encode(format string, body, payload interface{}) ([]byte, string, error) {
+	switch format {
+	case binding.MIMEXML, binding.MIMEXML2:
+		raw, err := encodeXML(body)
+		if err != nil {
+			return nil, "", err
+		}
+		return raw, format + "; charset=utf-8", nil
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		var raw []byte
+		if err := codec.NewEncoderBytes(&raw, new(codec.MsgpackHandle)).Encode(body); err != nil {
+			return nil, "", err
+		}
+		return raw, format, nil
+	case binding.MIMEPROTOBUF:
+		m, ok := body.(proto.Message)
+		if !ok {
+			if m, ok = payload.(proto.Message); !ok {
+				return nil, "", errUseJSON
+			}
+		}
+		raw, err := proto.Marshal(m)
+		if err != nil {
+			return nil, "", err
+		}
+		return raw, format, nil
+	}
+	return nil, "", errUseJSON
+}
+
+// encodeXML encoding/xml 不支持 map，body 或 JsonResponse.Data 为 map 时转换为 xmlMap
+func encodeXML(body interface{}) ([]byte, error) {
+	switch v := body.(type) {
+	case map[string]interface{}:
+		var buf bytes.Buffer
+		err := xml.NewEncoder(&buf).EncodeElement(xmlMap(v), xml.StartElement{Name: xml.Name{Local: "response"}})
+		return buf.Bytes(), err
+	case *response.JsonResponse:
+		if m, ok := v.Data.(map[string]interface{}); ok {
+			resp := *v
+			resp.Data = xmlMap(m)
+			body = &resp
+		}
+	}
+	return xml.Marshal(body)
+}
+
+// xmlMap 按 key 排序输出为子元素
+type xmlMap map[string]interface{}
+
+func (m xmlMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		v := m[k]
+		if nested, ok := v.(map[string]interface{}); ok {
+			v = xmlMap(nested)
+		}
+		if err := e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+type flatRenderer struct{}
+
+func (flatRenderer) Success(payload interface{}) interface{} {
+	return map[string]interface{}{"ok": true, "result": payload}
+}
+
+func (flatRenderer) Error(err response.Error) interface{} {
+	return map[string]interface{}{"ok": false, "error": err.GetMsg()}
+}
+
+type item struct {
+	Name string `json:"name" xml:"name" codec:"name"`
+}
+
+func newRenderMux(t *testing.T, options ...Option) Engine {
+	options = append(options, WithDisablePProf(), WithDisableSwagger(), WithDisablePrometheus())
+	mux, err := New("svc", zap.NewNop(), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := mux.Group("/api")
+	g.GET("/item", func(ctx Context) {
+		ctx.Payload(&item{Name: "phone"})
+	})
+	g.POST("/item", func(ctx Context) {
+		ctx.PayloadWithCode(http.StatusCreated, &item{Name: "phone"})
+	})
+	g.DELETE("/item", func(ctx Context) {
+		ctx.PayloadWithCode(http.StatusNoContent, nil)
+	})
+	g.GET("/empty", func(ctx Context) {
+		ctx.Payload(nil)
+	})
+	g.GET("/counts", func(ctx Context) {
+		ctx.Payload(map[string]int{"phone": 1})
+	})
+	g.GET("/proto", func(ctx Context) {
+		ctx.Payload(wrapperspb.String("phone"))
+	})
+	g.GET("/error", func(ctx Context) {
+		ctx.AbortWithError(response.NewErrorAutoMsg(http.StatusBadRequest, response.ParamBindError))
+	})
+	return mux
+}
+
+func doRender(mux Engine, method, path, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/svc/api/"+path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestPayload_Negotiate(t *testing.T) {
+	mux := newRenderMux(t)
+
+	w := doRender(mux, http.MethodGet, "item", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"code":0,"message":"","data":{"name":"phone"}}`, w.Body.String())
+
+	w = doRender(mux, http.MethodGet, "item", "application/xml")
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "<name>phone</name>")
+
+	w = doRender(mux, http.MethodGet, "item", "application/x-msgpack")
+	assert.Equal(t, "application/x-msgpack", w.Header().Get("Content-Type"))
+	var decoded struct {
+		Code int  `codec:"code"`
+		Data item `codec:"data"`
+	}
+	h := new(codec.MsgpackHandle)
+	h.RawToString = true
+	assert.NoError(t, codec.NewDecoderBytes(w.Body.Bytes(), h).Decode(&decoded))
+	assert.Equal(t, "phone", decoded.Data.Name)
+
+	// 不是 proto.Message 时使用 JSON
+	w = doRender(mux, http.MethodGet, "item", "application/x-protobuf")
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = doRender(mux, http.MethodGet, "proto", "application/x-protobuf")
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+	msg := new(wrapperspb.StringValue)
+	assert.NoError(t, proto.Unmarshal(w.Body.Bytes(), msg))
+	assert.Equal(t, "phone", msg.GetValue())
+
+	// payload 为 nil 时 Data 为空 map，XML 输出为空元素
+	w = doRender(mux, http.MethodGet, "empty", "application/xml")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "<JsonResponse><Code>0</Code><Message></Message><Data></Data></JsonResponse>", w.Body.String())
+
+	w = doRender(mux, http.MethodGet, "error", "application/xml")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "<Code>10004</Code>")
+
+	// XML 无法编码时返回 406，不回退为 JSON
+	w = doRender(mux, http.MethodGet, "counts", "application/xml")
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = doRender(mux, http.MethodPost, "item", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"phone"`)
+
+	w = doRender(mux, http.MethodDelete, "item", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestWithResponseRenderer(t *testing.T) {
+	var codes []int
+	mux := newRenderMux(t,
+		WithResponseRenderer(flatRenderer{}),
+		WithRecordMetrics(func(serverName string, method, uri string, httpCode, businessCode int, costSeconds float64, traceId string) {
+			codes = append(codes, httpCode, businessCode)
+		}),
+	)
+
+	w := doRender(mux, http.MethodGet, "item", "")
+	assert.JSONEq(t, `{"ok":true,"result":{"name":"phone"}}`, w.Body.String())
+
+	w = doRender(mux, http.MethodGet, "error", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, false, body["ok"])
+
+	// Telemetry 不依赖 body 的结构
+	assert.Equal(t, []int{http.StatusOK, 0, http.StatusBadRequest, response.ParamBindError}, codes)
+
+	// 自定义 body 为 map 时同样可以输出 XML
+	w = doRender(mux, http.MethodGet, "item", "application/xml")
+	assert.Equal(t, "<response><ok>true</ok><result><name>phone</name></result></response>", w.Body.String())
+}
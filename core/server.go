@@ -0,0 +1,281 @@
+package core
+
+import (
+	stdContext "context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/shutdown"
+)
+
+// Server 管理 http.Server 的生命周期
+//
+//	mux, _ := core.New("seckill", logger, core.WithHealthCheck("db", dbRepo.Ping))
+//	srv, err := core.NewServer(mux, ":8080",
+//		core.WithCloser("db", dbRepo.DbClose),
+//		core.WithCloser("cache", cacheRepo.Close),
+//		core.WithCloser("job", job.Close),
+//	)
+//	if err != nil { ... }
+//	if err = srv.Run(shutdown.NewHook()); err != nil { ... }
+//
+// 关闭顺序：health 返回 draining -> 等待 drainDelay -> 停止接收新请求并等待处理中的请求 -> 按注册的逆序执行 closers
+
+const (
+	_DefaultReadHeaderTimeout = 10 * time.Second
+	_DefaultReadTimeout       = 30 * time.Second
+	_DefaultIdleTimeout       = 120 * time.Second
+	_DefaultShutdownTimeout   = 30 * time.Second
+)
+
+type ServerOption func(*serverOption)
+
+type serverOption struct {
+	readHeaderTimeout time.Duration
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	shutdownTimeout   time.Duration
+	drainDelay        time.Duration
+
+	tlsConfig *tls.Config
+	certFile  string
+	keyFile   string
+
+	closers []closer
+}
+
+type closer struct {
+	name string
+	fn   func() error
+}
+
+// WithReadTimeout 读取整个请求的超时时间，默认 30s
+func WithReadTimeout(d time.Duration) ServerOption {
+	return func(opt *serverOption) {
+		opt.readTimeout = d
+	}
+}
+
+// WithReadHeaderTimeout 读取请求头的超时时间，默认 10s
+func WithReadHeaderTimeout(d time.Duration) ServerOption {
+	return func(opt *serverOption) {
+		opt.readHeaderTimeout = d
+	}
+}
+
+// WithWriteTimeout 写响应的超时时间，默认 0 不限制（SSE 等流式接口需要长时间写）
+func WithWriteTimeout(d time.Duration) ServerOption {
+	return func(opt *serverOption) {
+		opt.writeTimeout = d
+	}
+}
+
+// WithIdleTimeout keep-alive 连接的空闲时间，默认 120s
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return func(opt *serverOption) {
+		opt.idleTimeout = d
+	}
+}
+
+// WithShutdownTimeout Run 收到信号后等待请求处理完成的最长时间，默认 30s
+func WithShutdownTimeout(d time.Duration) ServerOption {
+	return func(opt *serverOption) {
+		opt.shutdownTimeout = d
+	}
+}
+
+// WithDrainDelay 标记 draining 后等待 d 再停止接收请求，让负载均衡通过健康检查摘除流量
+func WithDrainDelay(d time.Duration) ServerOption {
+	return func(opt *serverOption) {
+		opt.drainDelay = d
+	}
+}
+
+// WithTLS 使用证书文件开启 HTTPS
+func WithTLS(certFile, keyFile string) ServerOption {
+	return func(opt *serverOption) {
+		opt.certFile = certFile
+		opt.keyFile = keyFile
+	}
+}
+
+// WithTLSConfig 使用 tls.Config 开启 HTTPS，可与 WithTLS 同时使用
+func WithTLSConfig(config *tls.Config) ServerOption {
+	return func(opt *serverOption) {
+		opt.tlsConfig = config
+	}
+}
+
+// WithCloser 注册关闭时需要释放的资源，如 db.Repo.DbClose、cache.Repo.Close、ElasticJob.Close
+// 在 HTTP 请求全部处理完后按注册的逆序执行
+func WithCloser(name string, fn func() error) ServerOption {
+	return func(opt *serverOption) {
+		opt.closers = append(opt.closers, closer{name: name, fn: fn})
+	}
+}
+
+var _ Server = (*server)(nil)
+
+type Server interface {
+	// Addr 监听的地址
+	Addr() net.Addr
+	// Serve 开始处理请求，阻塞直到 Shutdown，正常关闭时返回 nil
+	Serve() error
+	// Shutdown 优雅关闭，ctx 超时后强制关闭剩余的连接
+	Shutdown(ctx stdContext.Context) error
+	// Run Serve 并在 hook 收到信号后 Shutdown
+	Run(hook shutdown.Hook) error
+}
+
+type server struct {
+	engine   Engine
+	srv      *http.Server
+	listener net.Listener
+	opt      *serverOption
+	logger   *zap.Logger
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// NewServer 监听 addr 并创建 Server，端口被占用等错误在此返回
+func NewServer(engine Engine, addr string, options ...ServerOption) (Server, error) {
+	if engine == nil {
+		return nil, errors.New("engine required")
+	}
+
+	opt := &serverOption{
+		readHeaderTimeout: _DefaultReadHeaderTimeout,
+		readTimeout:       _DefaultReadTimeout,
+		idleTimeout:       _DefaultIdleTimeout,
+		shutdownTimeout:   _DefaultShutdownTimeout,
+	}
+	for _, f := range options {
+		f(opt)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s failed: %w", addr, err)
+	}
+
+	logger := engine.logger()
+	return &server{
+		engine:   engine,
+		listener: listener,
+		opt:      opt,
+		logger:   logger,
+		srv: &http.Server{
+			Handler:           engine,
+			TLSConfig:         opt.tlsConfig,
+			ReadHeaderTimeout: opt.readHeaderTimeout,
+			ReadTimeout:       opt.readTimeout,
+			WriteTimeout:      opt.writeTimeout,
+			IdleTimeout:       opt.idleTimeout,
+			ErrorLog:          zap.NewStdLog(logger),
+		},
+	}, nil
+}
+
+func (s *server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *server) Serve() error {
+	s.logger.Info("http server started", zap.String("addr", s.Addr().String()))
+
+	var err error
+	if s.opt.tlsConfig != nil || s.opt.certFile != "" {
+		err = s.srv.ServeTLS(s.listener, s.opt.certFile, s.opt.keyFile)
+	} else {
+		err = s.srv.Serve(s.listener)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *server) Shutdown(ctx stdContext.Context) error {
+	s.shutdownOnce.Do(func() {
+		s.shutdownErr = s.shutdown(ctx)
+	})
+	return s.shutdownErr
+}
+
+func (s *server) shutdown(ctx stdContext.Context) error {
+	s.engine.setDraining(true)
+	s.logger.Info("http server draining", zap.Duration("drain_delay", s.opt.drainDelay))
+
+	if s.opt.drainDelay > 0 {
+		select {
+		case <-time.After(s.opt.drainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	// WebSocket 连接不受 http.Server.Shutdown 管理，主动关闭
+	s.engine.closeWS()
+	err := s.srv.Shutdown(ctx)
+	if err != nil {
+		// 超时后强制关闭
+		s.logger.Error("http server shutdown timeout, force close", zap.Error(err))
+		_ = s.srv.Close()
+	}
+
+	for i := len(s.opt.closers) - 1; i >= 0; i-- {
+		c := s.opt.closers[i]
+		if cErr := c.fn(); cErr != nil {
+			s.logger.Error("close resource failed", zap.String("name", c.name), zap.Error(cErr))
+			if err == nil {
+				err = fmt.Errorf("close %s failed: %w", c.name, cErr)
+			}
+		}
+	}
+
+	s.logger.Info("http server stopped")
+	return err
+}
+
+func (s *server) Run(hook shutdown.Hook) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve()
+	}()
+
+	signaled := make(chan struct{})
+	hookDone := make(chan struct{})
+	go func() {
+		defer close(hookDone)
+		hook.Close(func() {
+			close(signaled)
+		})
+	}()
+
+	select {
+	case err := <-serveErr:
+		// 未收到信号时 Serve 退出，如证书错误，停止监听信号，不留下阻塞的 goroutine
+		hook.Stop()
+		<-hookDone
+		_ = s.Shutdown(stdContext.Background())
+		return err
+	case <-signaled:
+	}
+
+	ctx, cancel := stdContext.WithTimeout(stdContext.Background(), s.opt.shutdownTimeout)
+	defer cancel()
+
+	err := s.Shutdown(ctx)
+	if sErr := <-serveErr; sErr != nil && err == nil {
+		err = sErr
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	stdContext "context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/shutdown"
+)
+
+func TestServer_Shutdown(t *testing.T) {
+	mux, err := New("svc", zap.NewNop(), WithDisablePProf(), WithDisableSwagger(), WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	mux.Group("/api").GET("/slow", func(ctx Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		ctx.Payload("done")
+	})
+
+	var closed []string
+	srv, err := NewServer(mux, "127.0.0.1:0",
+		WithDrainDelay(100*time.Millisecond),
+		WithCloser("db", func() error {
+			closed = append(closed, "db")
+			return nil
+		}),
+		WithCloser("cache", func() error {
+			closed = append(closed, "cache")
+			return errors.New("cache close failed")
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve()
+	}()
+	base := "http://" + srv.Addr().String() + "/svc"
+
+	// 处理中的请求在关闭时正常完成
+	slow := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(base + "/api/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		slow <- string(body)
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- srv.Shutdown(stdContext.Background())
+	}()
+
+	// drain delay 期间健康检查返回 draining
+	time.Sleep(20 * time.Millisecond)
+	resp, err := http.Get(base + "/system/health")
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Contains(t, string(body), `"status":"draining"`)
+	}
+
+	assert.Contains(t, <-slow, `"data":"done"`)
+	assert.EqualError(t, <-shutdownErr, "close cache failed: cache close failed")
+	assert.NoError(t, <-serveErr)
+	assert.Equal(t, []string{"cache", "db"}, closed)
+
+	// 重复调用返回相同结果
+	assert.Error(t, srv.Shutdown(stdContext.Background()))
+}
+
+func TestNewServer_AddrInUse(t *testing.T) {
+	mux, err := New("svc", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := NewServer(mux, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Shutdown(stdContext.Background())
+
+	_, err = NewServer(mux, srv.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestServer_RunServeError(t *testing.T) {
+	mux, err := New("svc", zap.NewNop(), WithDisablePProf(), WithDisableSwagger(), WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 证书不存在，Serve 立即返回
+	srv, err := NewServer(mux, "127.0.0.1:0", WithTLS("not_exist.crt", "not_exist.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := shutdown.NewHook()
+	assert.Error(t, srv.Run(hook))
+
+	// Run 返回前已停止监听信号
+	closed := make(chan struct{})
+	go func() {
+		hook.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("hook is still waiting for signals")
+	}
+}
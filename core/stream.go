@@ -0,0 +1,223 @@
+package core
+
+import (
+	stdContext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 流式返回
+// SSE、Stream、File 直接写 http.ResponseWriter，不经过 ResponseRenderer，
+// 需要 Server 的 WriteTimeout 为 0（默认）或大于连接时长。
+
+const (
+	_DefaultSSEHeartbeat = 15 * time.Second
+	_StreamBufferSize    = 32 * 1024
+)
+
+// SSESend 发送一个事件，data 为 string、[]byte 时原样发送，其余类型编码为 JSON
+// 客户端断开后返回 error，handler 应当退出
+type SSESend func(event string, data interface{}) error
+
+type SSEOption func(*sseOption)
+
+type sseOption struct {
+	heartbeat time.Duration
+}
+
+// WithSSEHeartbeat 心跳间隔，默认 15s，避免代理因空闲断开连接，为 0 时不发送
+func WithSSEHeartbeat(d time.Duration) SSEOption {
+	return func(opt *sseOption) {
+		opt.heartbeat = d
+	}
+}
+
+// SSE 以 text/event-stream 返回，handler 返回或客户端断开时结束。
+// Server 开始优雅关闭时取消请求的 ctx，之后 send 返回 error，handler 应当退出。
+//
+//	ctx.SSE(func(send core.SSESend) error {
+//		for result := range results {
+//			if err := send("order", result); err != nil {
+//				return err
+//			}
+//		}
+//		return nil
+//	})
+func (c *context) SSE(handler func(send SSESend) error, options ...SSEOption) {
+	opt := &sseOption{heartbeat: _DefaultSSEHeartbeat}
+	for _, f := range options {
+		f(opt)
+	}
+
+	reqCtx, cancel := stdContext.WithCancel(c.ctx.Request.Context())
+	defer cancel()
+	// handler 通过 Request.Context() 也能感知关闭
+	c.ctx.Request = c.ctx.Request.WithContext(reqCtx)
+	if drained, ok := c.ctx.Get(_Drained); ok {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-drained.(chan struct{}):
+				cancel()
+			case <-stop:
+			}
+		}()
+	}
+
+	w := c.ctx.Writer
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+	c.setStreamed(http.StatusOK)
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	var mu sync.Mutex
+	write := func(raw string) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err := reqCtx.Err(); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, raw); err != nil {
+			return err
+		}
+		w.Flush()
+		return nil
+	}
+
+	// 返回前等待心跳 goroutine 退出，之后 Writer 会被复用
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+	if opt.heartbeat > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(opt.heartbeat)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if write(": heartbeat\n\n") != nil {
+						return
+					}
+				case <-done:
+					return
+				case <-reqCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	send := func(event string, data interface{}) error {
+		raw, err := sseEvent(event, data)
+		if err != nil {
+			return err
+		}
+		return write(raw)
+	}
+
+	err := handler(send)
+	if err == nil || errors.Is(err, stdContext.Canceled) || reqCtx.Err() != nil {
+		return
+	}
+	// 连接仍在时把错误通知给客户端
+	c.Logger().Error("sse handler error", zap.Error(err))
+	_ = send("error", err.Error())
+}
+
+func sseEvent(event string, data interface{}) (string, error) {
+	var payload string
+	switch v := data.(type) {
+	case string:
+		payload = v
+	case []byte:
+		payload = string(v)
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("sse marshal data failed: %w", err)
+		}
+		payload = string(raw)
+	}
+
+	sb := strings.Builder{}
+	if event != "" {
+		sb.WriteString("event: ")
+		sb.WriteString(event)
+		sb.WriteByte('\n')
+	}
+	// 多行数据每行一个 data 字段
+	for _, line := range strings.Split(payload, "\n") {
+		sb.WriteString("data: ")
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+	return sb.String(), nil
+}
+
+// Stream 分块返回 reader 的内容，reader 为 io.Closer 时结束后关闭
+// 下载文件时通过 SetHeader 设置 Content-Disposition
+func (c *context) Stream(reader io.Reader, contentType string) error {
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	w := c.ctx.Writer
+	w.Header().Set("Content-Type", contentType)
+	c.setStreamed(http.StatusOK)
+	w.WriteHeader(http.StatusOK)
+
+	reqCtx := c.ctx.Request.Context()
+	buf := make([]byte, _StreamBufferSize)
+	for {
+		if err := reqCtx.Err(); err != nil {
+			return err
+		}
+
+		n, err := reader.Read(buf)
+		if n > 0 {
+			if _, wErr := w.Write(buf[:n]); wErr != nil {
+				return wErr
+			}
+			w.Flush()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			c.Logger().Error("stream read error", zap.Error(err))
+			return err
+		}
+	}
+}
+
+// File 返回文件，支持 Range 与 If-Modified-Since
+func (c *context) File(filepath string) {
+	http.ServeFile(c.ctx.Writer, c.ctx.Request, filepath)
+	c.setStreamed(c.ctx.Writer.Status())
+}
+
+// setStreamed 记录已返回，Telemetry 按正确返回统计
+func (c *context) setStreamed(httpCode int) {
+	c.ctx.Set(_Response, nil)
+	c.ctx.Set(_RespMeta, &responseMeta{httpCode: httpCode})
+}
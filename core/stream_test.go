@@ -0,0 +1,174 @@
+package core
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestSSE(t *testing.T) {
+	codes := make(chan int, 1)
+	mux, err := New("svc", zap.NewNop(), WithDisablePProf(), WithDisableSwagger(), WithDisablePrometheus(),
+		WithRecordMetrics(func(serverName string, method, uri string, httpCode, businessCode int, costSeconds float64, traceId string) {
+			codes <- httpCode
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopped := make(chan error, 1)
+	mux.Group("/api").GET("/orders/result", func(ctx Context) {
+		ctx.SSE(func(send SSESend) error {
+			if err := send("order", map[string]int{"id": 1}); err != nil {
+				return err
+			}
+			if err := send("", "line1\nline2"); err != nil {
+				return err
+			}
+			// 等待客户端断开
+			for {
+				if err := send("ping", "x"); err != nil {
+					stopped <- err
+					return err
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}, WithSSEHeartbeat(5*time.Millisecond))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/svc/api/orders/result")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	heartbeat := false
+	for len(lines) < 5 || !heartbeat {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			break
+		}
+		if strings.HasPrefix(line, ": heartbeat") {
+			heartbeat = true
+			continue
+		}
+		lines = append(lines, line)
+	}
+	assert.Equal(t, []string{
+		"event: order\n", "data: {\"id\":1}\n", "\n",
+		"data: line1\n", "data: line2\n",
+	}, lines[:5])
+	_ = resp.Body.Close()
+
+	select {
+	case err := <-stopped:
+		assert.Error(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("sse handler not stopped after client disconnect")
+	}
+
+	// Telemetry 按正确返回记录
+	select {
+	case code := <-codes:
+		assert.Equal(t, http.StatusOK, code)
+	case <-time.After(2 * time.Second):
+		t.Fatal("telemetry not recorded")
+	}
+}
+
+func TestSSE_Draining(t *testing.T) {
+	mux, err := New("svc", zap.NewNop(), WithDisablePProf(), WithDisableSwagger(), WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopped := make(chan error, 1)
+	mux.Group("/api").GET("/events", func(ctx Context) {
+		reqCtx := ctx.RequestContext().Request.Context()
+		ctx.SSE(func(send SSESend) error {
+			if err := send("ready", "1"); err != nil {
+				return err
+			}
+			// 阻塞的 handler 通过 Request.Context() 感知关闭
+			<-ctx.RequestContext().Request.Context().Done()
+			stopped <- send("tick", "x")
+			return nil
+		})
+		assert.NoError(t, reqCtx.Err())
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/svc/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "event: ready\n", line)
+
+	mux.setDraining(true)
+	select {
+	case err := <-stopped:
+		assert.Error(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("sse handler not stopped after draining")
+	}
+
+	// 服务端结束响应
+	_, err = ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+}
+
+func TestStreamAndFile(t *testing.T) {
+	mux, err := New("svc", zap.NewNop(), WithDisablePProf(), WithDisableSwagger(), WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(t.TempDir(), "orders.csv")
+	assert.NoError(t, ioutil.WriteFile(name, []byte("id,status\n1,paid\n"), 0644))
+
+	g := mux.Group("/api")
+	g.GET("/export", func(ctx Context) {
+		ctx.SetHeader("Content-Disposition", `attachment; filename="orders.csv"`)
+		_ = ctx.Stream(strings.NewReader(strings.Repeat("a", 100*1024)), "text/csv")
+	})
+	g.GET("/file", func(ctx Context) {
+		ctx.File(name)
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/svc/api/export", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, 100*1024, w.Body.Len())
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/svc/api/file", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "id,status\n1,paid\n", w.Body.String())
+
+	_ = os.Remove(name)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/svc/api/file", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
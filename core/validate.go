@@ -0,0 +1,134 @@
+package core
+
+import (
+	stdContext "context"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gogf/gf/v2/util/gvalid"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+// 参数校验
+// 规则写在 `v` tag 中，语法同 gvalid：`v:"[别名@]规则1|规则2[#错误信息1|错误信息2]"`
+//
+//	type LoginParams struct {
+//		UserName string `form:"user_name" v:"required|length:3,20"`
+//	}
+//
+// 未在 tag 中指定错误信息的规则使用 response.RuleText 注册的信息，
+// 错误详情中的字段名依次取 json、form、uri tag，都没有时为字段名。
+
+// fieldMeta 顶层字段的展示名与错误信息，key 为 gvalid 中的字段名（别名或字段名）
+type fieldMeta struct {
+	display  string
+	messages map[string]string
+}
+
+// validate 校验 obj，失败时返回各字段的错误详情，按字段、规则排序
+func validate(ctx stdContext.Context, obj interface{}) []response.FieldError {
+	metas := parseFieldMetas(obj)
+
+	messages := make(gvalid.CustomMsg, len(metas))
+	for name, meta := range metas {
+		messages[name] = meta.messages
+	}
+
+	vErr := gvalid.New().Data(obj).Messages(messages).Run(ctx)
+	if vErr == nil {
+		return nil
+	}
+
+	var details []response.FieldError
+	for _, item := range vErr.Items() {
+		for name, rules := range item {
+			display := name
+			if meta, ok := metas[name]; ok {
+				display = meta.display
+			}
+			for rule, err := range rules {
+				details = append(details, response.FieldError{
+					Field:   display,
+					Rule:    rule,
+					Message: err.Error(),
+				})
+			}
+		}
+	}
+
+	// gvalid 的结果是 map，排序后同样的输入返回同样的错误信息
+	sort.Slice(details, func(i, j int) bool {
+		if details[i].Field != details[j].Field {
+			return details[i].Field < details[j].Field
+		}
+		return details[i].Rule < details[j].Rule
+	})
+	return details
+}
+
+func parseFieldMetas(obj interface{}) map[string]*fieldMeta {
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	metas := make(map[string]*fieldMeta)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := validationTag(field)
+		if tag == "" {
+			continue
+		}
+
+		alias, rules, msg := gvalid.ParseTagValue(tag)
+		name := field.Name
+		if alias != "" {
+			name = alias
+		}
+		display := displayName(field)
+
+		// tag 中的错误信息优先，gvalid 的 Messages 会整体覆盖 tag 中的信息，所以一并放入
+		var tagMessages []string
+		if msg != "" {
+			tagMessages = strings.Split(msg, "|")
+		}
+		messages := make(map[string]string)
+		for k, rule := range strings.Split(rules, "|") {
+			ruleKey := strings.TrimSpace(strings.SplitN(rule, ":", 2)[0])
+			if ruleKey == "" {
+				continue
+			}
+			text := response.RuleText(ruleKey)
+			if k < len(tagMessages) && strings.TrimSpace(tagMessages[k]) != "" {
+				text = strings.TrimSpace(tagMessages[k])
+			}
+			messages[ruleKey] = strings.Replace(text, "{attribute}", display, -1)
+		}
+		metas[name] = &fieldMeta{display: display, messages: messages}
+	}
+	return metas
+}
+
+func validationTag(field reflect.StructField) string {
+	for _, key := range gvalid.GetTags() {
+		if tag := field.Tag.Get(key); tag != "" {
+			return tag
+		}
+	}
+	return ""
+}
+
+func displayName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form", "uri"} {
+		name := strings.Split(field.Tag.Get(key), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	stdContext "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+type createOrderParams struct {
+	ShopID   int    `uri:"shop_id" v:"required|min:1"`
+	Channel  string `form:"channel" v:"in:app,web"`
+	UserName string `json:"user_name" v:"required|length:3,20"`
+	SkuCode  string `json:"sku_code" v:"required#请选择商品"`
+}
+
+func newValidateMux(t *testing.T) Engine {
+	mux, err := New("svc", zap.NewNop(), WithDisablePProf(), WithDisableSwagger(), WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Group("/api").POST("/shops/:shop_id/orders", func(ctx Context) {
+		params := new(createOrderParams)
+		if err := ctx.BindAndValidate(params); err != nil {
+			return
+		}
+		ctx.Payload(params)
+	})
+	return mux
+}
+
+func postOrder(mux Engine, path, body string) (int, *response.JsonResponse) {
+	req := httptest.NewRequest(http.MethodPost, "/svc/api/shops/"+path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	resp := new(response.JsonResponse)
+	_ = json.Unmarshal(w.Body.Bytes(), resp)
+	return w.Code, resp
+}
+
+func TestContext_BindAndValidate(t *testing.T) {
+	mux := newValidateMux(t)
+
+	code, resp := postOrder(mux, "3/orders?channel=app", `{"user_name":"alice","sku_code":"s9"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{
+		"ShopID":    float64(3),
+		"Channel":   "app",
+		"user_name": "alice",
+		"sku_code":  "s9",
+	}, resp.Data)
+
+	code, resp = postOrder(mux, "3/orders?channel=tv", `{"user_name":"al"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, response.ParamBindError, resp.Code)
+	assert.Equal(t, response.Text(response.ParamBindError), resp.Message)
+
+	raw, _ := json.Marshal(resp.Data)
+	var details []response.FieldError
+	assert.NoError(t, json.Unmarshal(raw, &details))
+	assert.Equal(t, []response.FieldError{
+		{Field: "channel", Rule: "in", Message: "channel应当为app,web中的一个"},
+		{Field: "sku_code", Rule: "required", Message: "请选择商品"},
+		{Field: "user_name", Rule: "length", Message: "user_name长度应当为3到20个字符"},
+	}, details)
+
+	// 反序列化失败
+	code, resp = postOrder(mux, "3/orders", `{"user_name":`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, response.ParamBindError, resp.Code)
+}
+
+func TestValidate_Order(t *testing.T) {
+	type params struct {
+		Name  string `json:"name" v:"required"`
+		Email string `json:"email" v:"required|email"`
+	}
+
+	ctx := stdContext.Background()
+	for i := 0; i < 20; i++ {
+		var got []string
+		for _, d := range validate(ctx, &params{Email: "x"}) {
+			got = append(got, d.Field+":"+d.Rule)
+		}
+		assert.Equal(t, []string{"email:email", "name:required"}, got)
+	}
+
+	mux, err := New("svc", zap.NewNop(), WithDisablePProf(), WithDisableSwagger(), WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	mux.Group("/api").POST("/users", func(ctx Context) {
+		if err := ctx.BindAndValidate(new(params)); err != nil {
+			messages = append(messages, err.Error())
+		}
+	})
+	for i := 0; i < 20; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/svc/api/users", strings.NewReader(`{"email":"x"}`))
+		req.Header.Set("Content-Type", "application/json")
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if assert.Len(t, messages, 20) {
+		for _, m := range messages {
+			assert.Equal(t, messages[0], m)
+		}
+	}
+}
@@ -0,0 +1,539 @@
+package core
+
+import (
+	stdContext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/prometheus_helper"
+)
+
+// WebSocket
+// 在 RouterGroup 的中间件（如鉴权）执行后升级连接，handler 返回时关闭连接。
+//
+//	g := mux.Group("/ws")
+//	g.Use(core.WrapAuthHandler(middleware.Jwt))
+//	g.WS("/seckill", func(conn core.WSConn) {
+//		conn.Subscribe("activity:1")
+//		for {
+//			_, msg, err := conn.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			...
+//		}
+//	})
+//
+//	// 其他地方推送
+//	_ = mux.Hub().Publish("activity:1", countdown)
+//	_ = mux.Hub().SendToUser(userID, order)
+
+const (
+	_DefaultWSPingInterval = 30 * time.Second
+	_DefaultWSWriteTimeout = 10 * time.Second
+	_DefaultWSReadLimit    = 64 * 1024
+	_WSSendBufferSize      = 256
+)
+
+var (
+	ErrWSClosed         = errors.New("websocket connection closed. ")
+	ErrWSSendBufferFull = errors.New("websocket send buffer full. ")
+)
+
+// WSHandler 处理一个 WebSocket 连接
+type WSHandler func(conn WSConn)
+
+type WSOption func(*wsOption)
+
+type wsOption struct {
+	pingInterval time.Duration
+	writeTimeout time.Duration
+	readLimit    int64
+	checkOrigin  func(r *http.Request) bool
+}
+
+// WithWSPingInterval ping 的间隔，超过两倍间隔未收到 pong 时断开，默认 30s
+func WithWSPingInterval(d time.Duration) WSOption {
+	return func(opt *wsOption) {
+		opt.pingInterval = d
+	}
+}
+
+// WithWSWriteTimeout 单条消息的写超时，默认 10s
+func WithWSWriteTimeout(d time.Duration) WSOption {
+	return func(opt *wsOption) {
+		opt.writeTimeout = d
+	}
+}
+
+// WithWSReadLimit 单条消息的最大字节数，默认 64KB
+func WithWSReadLimit(limit int64) WSOption {
+	return func(opt *wsOption) {
+		opt.readLimit = limit
+	}
+}
+
+// WithWSCheckOrigin 校验 Origin，默认只允许同源
+func WithWSCheckOrigin(check func(r *http.Request) bool) WSOption {
+	return func(opt *wsOption) {
+		opt.checkOrigin = check
+	}
+}
+
+var _ WSConn = (*wsConn)(nil)
+
+// WSConn WebSocket 连接，写方法可并发调用
+type WSConn interface {
+	// UserID 鉴权中间件设置的 UserID
+	UserID() int64
+	// UserName 鉴权中间件设置的 UserName
+	UserName() string
+	// Logger 带 trace 信息的 Logger
+	Logger() *zap.Logger
+	// Context 连接断开后 Done
+	Context() stdContext.Context
+
+	// ReadMessage 读取一条消息，只能在 handler 中调用
+	ReadMessage() (messageType int, data []byte, err error)
+	// ReadJSON 读取一条 JSON 消息
+	ReadJSON(v interface{}) error
+	// WriteMessage 放入发送队列，队列满时关闭连接并返回 ErrWSSendBufferFull
+	WriteMessage(messageType int, data []byte) error
+	// WriteJSON 以 TextMessage 发送 JSON
+	WriteJSON(v interface{}) error
+
+	// Subscribe 订阅 topic，接收 Hub.Publish 的消息
+	Subscribe(topics ...string)
+	// Unsubscribe 取消订阅
+	Unsubscribe(topics ...string)
+	Close() error
+}
+
+// WSHub 管理连接，按用户或 topic 推送
+type WSHub interface {
+	// SendToUser 推送给用户的所有连接，返回推送的连接数
+	SendToUser(userID int64, v interface{}) (int, error)
+	// Publish 推送给订阅 topic 的所有连接，返回推送的连接数
+	Publish(topic string, v interface{}) (int, error)
+	// Count 当前的连接数
+	Count() int
+}
+
+func (r *router) WS(relativePath string, handler WSHandler, options ...WSOption) {
+	opt := &wsOption{
+		pingInterval: _DefaultWSPingInterval,
+		writeTimeout: _DefaultWSWriteTimeout,
+		readLimit:    _DefaultWSReadLimit,
+	}
+	for _, f := range options {
+		f(opt)
+	}
+	upgrader := &websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     opt.checkOrigin,
+	}
+
+	r.GET(relativePath, func(ctx Context) {
+		c := ctx.RequestContext()
+		ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// Upgrade 已返回错误响应
+			ctx.Logger().Warn("websocket upgrade failed", zap.Error(err))
+			c.Abort()
+			ctx.(*context).setStreamed(c.Writer.Status())
+			return
+		}
+		ctx.(*context).setStreamed(http.StatusSwitchingProtocols)
+
+		conn := newWSConn(ctx, ws, r.hub, opt)
+		defer conn.Close()
+		if !r.hub.register(conn) {
+			// Server 正在关闭
+			return
+		}
+
+		handler(conn)
+	})
+}
+
+type wsConn struct {
+	ws     *websocket.Conn
+	hub    *wsHub
+	opt    *wsOption
+	logger *zap.Logger
+
+	userID   int64
+	userName string
+
+	ctx    stdContext.Context
+	cancel stdContext.CancelFunc
+	send   chan wsMessage
+
+	mu     sync.Mutex
+	topics map[string]struct{}
+	closed bool
+}
+
+type wsMessage struct {
+	messageType int
+	data        []byte
+}
+
+func newWSConn(ctx Context, ws *websocket.Conn, hub *wsHub, opt *wsOption) *wsConn {
+	connCtx, cancel := stdContext.WithCancel(ctx.RequestContext().Request.Context())
+	c := &wsConn{
+		ws:       ws,
+		hub:      hub,
+		opt:      opt,
+		logger:   ctx.Logger(),
+		userID:   ctx.UserID(),
+		userName: ctx.UserName(),
+		ctx:      connCtx,
+		cancel:   cancel,
+		send:     make(chan wsMessage, _WSSendBufferSize),
+		topics:   make(map[string]struct{}),
+	}
+
+	ws.SetReadLimit(opt.readLimit)
+	if opt.pingInterval > 0 {
+		pongWait := 2 * opt.pingInterval
+		_ = ws.SetReadDeadline(time.Now().Add(pongWait))
+		ws.SetPongHandler(func(string) error {
+			return ws.SetReadDeadline(time.Now().Add(pongWait))
+		})
+	}
+	go c.writePump()
+	return c
+}
+
+// writePump 唯一的写 goroutine，发送队列中的消息与 ping
+// 退出时关闭底层连接，阻塞在 ReadMessage 的 handler 随之返回
+func (c *wsConn) writePump() {
+	defer c.ws.Close()
+
+	var ping <-chan time.Time
+	if c.opt.pingInterval > 0 {
+		ticker := time.NewTicker(c.opt.pingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	for {
+		select {
+		case msg := <-c.send:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(c.opt.writeTimeout))
+			if err := c.ws.WriteMessage(msg.messageType, msg.data); err != nil {
+				c.logger.Warn("websocket write failed", zap.Error(err))
+				_ = c.Close()
+				return
+			}
+			c.hub.metrics.messages.WithLabelValues("out").Inc()
+		case <-ping:
+			deadline := time.Now().Add(c.opt.writeTimeout)
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				_ = c.Close()
+				return
+			}
+		case <-c.ctx.Done():
+			deadline := time.Now().Add(time.Second)
+			_ = c.ws.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+			return
+		}
+	}
+}
+
+func (c *wsConn) UserID() int64 {
+	return c.userID
+}
+
+func (c *wsConn) UserName() string {
+	return c.userName
+}
+
+func (c *wsConn) Logger() *zap.Logger {
+	return c.logger
+}
+
+func (c *wsConn) Context() stdContext.Context {
+	return c.ctx
+}
+
+func (c *wsConn) ReadMessage() (int, []byte, error) {
+	messageType, data, err := c.ws.ReadMessage()
+	if err != nil {
+		_ = c.Close()
+		return messageType, data, err
+	}
+	c.hub.metrics.messages.WithLabelValues("in").Inc()
+	return messageType, data, nil
+}
+
+func (c *wsConn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *wsConn) WriteMessage(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrWSClosed
+	}
+
+	select {
+	case c.send <- wsMessage{messageType: messageType, data: data}:
+		return nil
+	default:
+		// 客户端消费过慢，断开避免占用内存
+		c.logger.Warn("websocket send buffer full, close connection", zap.Int64("user_id", c.userID))
+		c.closeLocked()
+		return ErrWSSendBufferFull
+	}
+}
+
+func (c *wsConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("websocket marshal failed: %w", err)
+	}
+	return c.WriteMessage(websocket.TextMessage, data)
+}
+
+// 加锁顺序为 wsConn.mu -> wsHub.mu，wsHub 持有锁时不调用 wsConn 的方法
+
+func (c *wsConn) Subscribe(topics ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	for _, topic := range topics {
+		c.topics[topic] = struct{}{}
+	}
+	c.hub.subscribe(c, topics...)
+}
+
+func (c *wsConn) Unsubscribe(topics ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, topic := range topics {
+		delete(c.topics, topic)
+	}
+	c.hub.unsubscribe(c, topics...)
+}
+
+func (c *wsConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeLocked()
+	return nil
+}
+
+func (c *wsConn) closeLocked() {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.cancel()
+
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	c.hub.unregister(c, topics)
+}
+
+type wsMetrics struct {
+	connections prometheus.Gauge
+	messages    *prometheus.CounterVec
+}
+
+func newWSMetrics(serverName string) *wsMetrics {
+	labels := prometheus.Labels{
+		"system_name": serverName,
+	}
+
+	m := &wsMetrics{
+		connections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "core_websocket_connections",
+			Help:        "Number of open websocket connections.",
+			ConstLabels: labels,
+		}),
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "core_websocket_messages_total",
+			Help:        "Number of websocket messages, partitioned by direction (in or out).",
+			ConstLabels: labels,
+		}, []string{"direction"}),
+	}
+
+	// 相同 serverName 多次 New 时，复用已注册的指标
+	m.connections = prometheus_helper.RegisterOrExisting(m.connections).(prometheus.Gauge)
+	m.messages = prometheus_helper.RegisterOrExisting(m.messages).(*prometheus.CounterVec)
+	return m
+}
+
+var _ WSHub = (*wsHub)(nil)
+
+type wsConnSet map[*wsConn]struct{}
+
+type wsHub struct {
+	mu      sync.RWMutex
+	conns   wsConnSet
+	groups  map[string]wsConnSet // key 为 userKey 或 topicKey
+	metrics *wsMetrics
+	closed  bool
+}
+
+func newWSHub(serverName string) *wsHub {
+	return &wsHub{
+		conns:   make(wsConnSet),
+		groups:  make(map[string]wsConnSet),
+		metrics: newWSMetrics(serverName),
+	}
+}
+
+func userKey(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
+}
+
+func topicKey(topic string) string {
+	return "topic:" + topic
+}
+
+// register closeAll 之后返回 false，连接需要关闭
+func (h *wsHub) register(c *wsConn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+	h.conns[c] = struct{}{}
+	if c.userID != 0 {
+		h.add(userKey(c.userID), c)
+	}
+	h.metrics.connections.Inc()
+	return true
+}
+
+// closeAll 关闭所有连接，之后不再接受新连接
+// 被 Hijack 的连接不受 http.Server.Shutdown 管理，由 Server 关闭时调用
+func (h *wsHub) closeAll() {
+	h.mu.Lock()
+	h.closed = true
+	conns := copyConns(h.conns)
+	h.mu.Unlock()
+
+	for _, c := range conns {
+		_ = c.Close()
+	}
+}
+
+func (h *wsHub) unregister(c *wsConn, topics []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.conns[c]; !ok {
+		return
+	}
+	delete(h.conns, c)
+	h.remove(userKey(c.userID), c)
+	for _, topic := range topics {
+		h.remove(topicKey(topic), c)
+	}
+	h.metrics.connections.Dec()
+}
+
+func (h *wsHub) subscribe(c *wsConn, topics ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		h.add(topicKey(topic), c)
+	}
+}
+
+func (h *wsHub) unsubscribe(c *wsConn, topics ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		h.remove(topicKey(topic), c)
+	}
+}
+
+func (h *wsHub) SendToUser(userID int64, v interface{}) (int, error) {
+	h.mu.RLock()
+	conns := copyConns(h.groups[userKey(userID)])
+	h.mu.RUnlock()
+	return broadcast(conns, v)
+}
+
+func (h *wsHub) Publish(topic string, v interface{}) (int, error) {
+	h.mu.RLock()
+	conns := copyConns(h.groups[topicKey(topic)])
+	h.mu.RUnlock()
+	return broadcast(conns, v)
+}
+
+func (h *wsHub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// broadcast 只编码一次，发送失败的连接会被关闭，不影响其他连接
+func broadcast(conns []*wsConn, v interface{}) (int, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0, fmt.Errorf("websocket marshal failed: %w", err)
+	}
+
+	sent := 0
+	for _, c := range conns {
+		if c.WriteMessage(websocket.TextMessage, data) == nil {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func (h *wsHub) add(key string, c *wsConn) {
+	set, ok := h.groups[key]
+	if !ok {
+		set = make(wsConnSet)
+		h.groups[key] = set
+	}
+	set[c] = struct{}{}
+}
+
+func (h *wsHub) remove(key string, c *wsConn) {
+	set, ok := h.groups[key]
+	if !ok {
+		return
+	}
+	delete(set, c)
+	if len(set) == 0 {
+		delete(h.groups, key)
+	}
+}
+
+func copyConns(set wsConnSet) []*wsConn {
+	conns := make([]*wsConn, 0, len(set))
+	for c := range set {
+		conns = append(conns, c)
+	}
+	return conns
+}
@@ -0,0 +1,194 @@
+package core
+
+import (
+	stdContext "context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+func TestWebSocket(t *testing.T) {
+	mux, err := New("ws_test", zap.NewNop(), WithDisablePProf(), WithDisableSwagger(), WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := mux.Group("/ws")
+	g.Use(WrapAuthHandler(func(ctx Context) (int64, string, response.Error) {
+		userID := cast.ToInt64(ctx.GetHeader("X-User"))
+		if userID == 0 {
+			return 0, "", response.NewErrorAutoMsg(http.StatusUnauthorized, response.AuthorizationError)
+		}
+		return userID, "user" + ctx.GetHeader("X-User"), nil
+	}))
+	g.WS("/seckill", func(conn WSConn) {
+		conn.Subscribe("activity:1")
+		_ = conn.WriteJSON(map[string]interface{}{"hello": conn.UserName()})
+		for {
+			var msg map[string]string
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg["op"] == "leave" {
+				conn.Unsubscribe("activity:1")
+				_ = conn.WriteJSON(map[string]string{"op": "left"})
+			}
+		}
+	}, WithWSPingInterval(20*time.Millisecond))
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws_test/ws/seckill"
+
+	// 客户端持续读取，控制帧（ping）在读取时处理
+	messages := make(map[*websocket.Conn]chan string)
+	dial := func(user string, onPing func()) *websocket.Conn {
+		conn, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"X-User": {user}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if onPing != nil {
+			conn.SetPingHandler(func(data string) error {
+				onPing()
+				return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+			})
+		}
+
+		ch := make(chan string, 16)
+		messages[conn] = ch
+		go func() {
+			defer close(ch)
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				ch <- string(data)
+			}
+		}()
+		return conn
+	}
+	read := func(conn *websocket.Conn) string {
+		select {
+		case msg := <-messages[conn]:
+			return msg
+		case <-time.After(2 * time.Second):
+			t.Error("read timeout")
+			return ""
+		}
+	}
+
+	// 未通过鉴权时不升级
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.Error(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	pinged := make(chan struct{}, 1)
+	alice := dial("1", func() {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+	})
+	defer alice.Close()
+	assert.JSONEq(t, `{"hello":"user1"}`, read(alice))
+
+	bob := dial("2", nil)
+	defer bob.Close()
+	assert.JSONEq(t, `{"hello":"user2"}`, read(bob))
+	assert.Equal(t, 2, mux.Hub().Count())
+	assert.Equal(t, float64(2), testutil.ToFloat64(mux.(*engine).hub.metrics.connections))
+
+	n, err := mux.Hub().SendToUser(2, map[string]int{"order": 9})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.JSONEq(t, `{"order":9}`, read(bob))
+
+	n, _ = mux.Hub().Publish("activity:1", map[string]int{"countdown": 3})
+	assert.Equal(t, 2, n)
+	assert.JSONEq(t, `{"countdown":3}`, read(alice))
+	assert.JSONEq(t, `{"countdown":3}`, read(bob))
+
+	assert.NoError(t, bob.WriteJSON(map[string]string{"op": "leave"}))
+	assert.JSONEq(t, `{"op":"left"}`, read(bob))
+	n, _ = mux.Hub().Publish("activity:1", map[string]int{"countdown": 2})
+	assert.Equal(t, 1, n)
+	assert.JSONEq(t, `{"countdown":2}`, read(alice))
+
+	// 响应 pong 的连接保持
+	time.Sleep(100 * time.Millisecond)
+	_, _ = mux.Hub().SendToUser(1, "tick")
+	assert.Equal(t, `"tick"`, read(alice))
+	select {
+	case <-pinged:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no ping received")
+	}
+
+	// 客户端断开后从 Hub 移除
+	_ = bob.Close()
+	assert.Eventually(t, func() bool {
+		return mux.Hub().Count() == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	n, _ = mux.Hub().SendToUser(2, "bye")
+	assert.Equal(t, 0, n)
+	assert.True(t, testutil.ToFloat64(mux.(*engine).hub.metrics.messages.WithLabelValues("in")) >= 1)
+}
+
+func TestWebSocket_Shutdown(t *testing.T) {
+	mux, err := New("ws_shutdown_test", zap.NewNop(), WithDisablePProf(), WithDisableSwagger(), WithDisablePrometheus())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	returned := make(chan struct{}, 2)
+	mux.Group("/ws").WS("/echo", func(conn WSConn) {
+		defer func() { returned <- struct{}{} }()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	srv, err := NewServer(mux, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = srv.Serve()
+	}()
+
+	url := "ws://" + srv.Addr().String() + "/ws_shutdown_test/ws/echo"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	assert.Eventually(t, func() bool { return mux.Hub().Count() == 1 }, time.Second, 5*time.Millisecond)
+
+	// 被 Hijack 的连接在 Shutdown 时关闭，handler 返回
+	assert.NoError(t, srv.Shutdown(stdContext.Background()))
+	select {
+	case <-returned:
+	case <-time.After(2 * time.Second):
+		t.Fatal("websocket handler not returned after shutdown")
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err)
+	assert.Equal(t, 0, mux.Hub().Count())
+}
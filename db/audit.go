@@ -0,0 +1,387 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 审计
+// 1. 创建时填充 created_by / created_by_name / updated_by / updated_by_name，更新时填充 updated_by / updated_by_name，
+//    操作人由 AuditOperator 从 statement ctx 中取出，未配置时不填充。
+// 2. 配置 AuditSink 与 AuditTables 后，记录这些表更新前后发生变化的字段，Sink 在同一事务中执行，写入失败时更新回滚。
+//    更新前的数据在事务中使用 SELECT ... FOR UPDATE 读取，避免并发更新导致记录的旧值不准确。
+//
+//	db.New(&db.DBConfig{
+//		AuditOperator: core.Operator,
+//		AuditSink:     db.NewTableSink("audit_log"),
+//		AuditTables:   []string{"order"},
+//	})
+//	repo.DB(svcCtx.Context()).Model(order).Updates(map[string]interface{}{"status": 2})
+//
+// 软删除：模型内嵌 db.Model 即可，Delete 时只设置 deleted_at，查询自动过滤已删除的记录。
+
+const (
+	_CreatedBy     = "created_by"
+	_CreatedByName = "created_by_name"
+	_UpdatedBy     = "updated_by"
+	_UpdatedByName = "updated_by_name"
+
+	_AuditBeforeKey = "db:audit:before"
+
+	// AuditActionUpdate 更新
+	AuditActionUpdate = "update"
+)
+
+// Model 带审计字段与软删除的基础模型
+type Model struct {
+	ID            int64          `gorm:"primaryKey" json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	CreatedBy     int64          `json:"created_by"`
+	CreatedByName string         `gorm:"size:64" json:"created_by_name"`
+	UpdatedBy     int64          `json:"updated_by"`
+	UpdatedByName string         `gorm:"size:64" json:"updated_by_name"`
+}
+
+// AuditChange 字段变化
+type AuditChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// AuditRecord 一行数据的变更记录
+type AuditRecord struct {
+	Table      string                 `json:"table"`
+	PrimaryKey string                 `json:"primary_key"`
+	Action     string                 `json:"action"`
+	Changes    map[string]AuditChange `json:"changes"`
+	UserID     int64                  `json:"user_id"`
+	UserName   string                 `json:"user_name"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditSink 变更记录的去向，tx 与更新语句处于同一事务
+type AuditSink interface {
+	Write(tx *gorm.DB, records []*AuditRecord) error
+}
+
+// AuditLog 审计表
+type AuditLog struct {
+	ID         int64     `gorm:"primaryKey"`
+	Target     string    `gorm:"column:table_name;size:64;index"`
+	PrimaryKey string    `gorm:"size:64;index"`
+	Action     string    `gorm:"size:16"`
+	Changes    string    `gorm:"type:text"`
+	UserID     int64     `gorm:"index"`
+	UserName   string    `gorm:"size:64"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+type tableSink struct {
+	table string
+}
+
+// NewTableSink 写入审计表，表结构见 AuditLog，需提前通过迁移或 AutoMigrate 创建
+func NewTableSink(table string) AuditSink {
+	return &tableSink{table: table}
+}
+
+func (s *tableSink) Write(tx *gorm.DB, records []*AuditRecord) error {
+	logs := make([]*AuditLog, 0, len(records))
+	for _, r := range records {
+		changes, err := json.Marshal(r.Changes)
+		if err != nil {
+			return err
+		}
+		logs = append(logs, &AuditLog{
+			Target:     r.Table,
+			PrimaryKey: r.PrimaryKey,
+			Action:     r.Action,
+			Changes:    string(changes),
+			UserID:     r.UserID,
+			UserName:   r.UserName,
+			CreatedAt:  r.CreatedAt,
+		})
+	}
+	return tx.Table(s.table).Create(&logs).Error
+}
+
+// AuditOperator 从 ctx 中取出操作人，没有操作人时 ok 为 false
+type AuditOperator func(ctx context.Context) (id int64, name string, ok bool)
+
+type AuditOption func(*auditPlugin)
+
+// WithOperator 设置操作人的来源，为空时不填充审计字段，变更记录中没有操作人
+func WithOperator(operator AuditOperator) AuditOption {
+	return func(p *auditPlugin) {
+		p.operator = operator
+	}
+}
+
+// WithAuditSink 设置变更记录的去向，为空时只填充审计字段
+func WithAuditSink(sink AuditSink) AuditOption {
+	return func(p *auditPlugin) {
+		p.sink = sink
+	}
+}
+
+// WithAuditTables 记录这些表的变更，为空时不记录，避免每次更新都多一次查询
+func WithAuditTables(tables ...string) AuditOption {
+	return func(p *auditPlugin) {
+		for _, t := range tables {
+			p.tables[t] = true
+		}
+	}
+}
+
+type auditPlugin struct {
+	operator AuditOperator
+	sink     AuditSink
+	tables   map[string]bool
+}
+
+func NewAuditPlugin(opts ...AuditOption) gorm.Plugin {
+	p := &auditPlugin{
+		tables: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *auditPlugin) Name() string {
+	return "db:audit"
+}
+
+func (p *auditPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		callback gormRegister
+		hook     gormHookFunc
+		name     string
+	}{
+		{cb.Create().Before("gorm:create"), p.beforeCreate, "before:create"},
+		{cb.Update().Before("gorm:update"), p.beforeUpdate, "before:update"},
+		// 在提交事务前写入，失败时回滚
+		{cb.Update().After("gorm:update").Before("gorm:commit_or_rollback_transaction"), p.afterUpdate, "after:update"},
+	}
+
+	for _, h := range hooks {
+		if err := h.callback.Register("db:audit:"+h.name, h.hook); err != nil {
+			return fmt.Errorf("callback register %s failed: %w", h.name, err)
+		}
+	}
+	return nil
+}
+
+func (p *auditPlugin) operatorOf(ctx context.Context) (int64, string, bool) {
+	if p.operator == nil || ctx == nil {
+		return 0, "", false
+	}
+	return p.operator(ctx)
+}
+
+func (p *auditPlugin) beforeCreate(tx *gorm.DB) {
+	stmt := tx.Statement
+	if tx.Error != nil || stmt.Schema == nil {
+		return
+	}
+	userID, userName, ok := p.operatorOf(stmt.Context)
+	if !ok {
+		return
+	}
+
+	values := map[string]interface{}{
+		_CreatedBy:     userID,
+		_CreatedByName: userName,
+		_UpdatedBy:     userID,
+		_UpdatedByName: userName,
+	}
+	for name, value := range values {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			continue
+		}
+
+		// 只填充未设置的字段
+		switch stmt.ReflectValue.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < stmt.ReflectValue.Len(); i++ {
+				setIfZero(tx, field, stmt.ReflectValue.Index(i), value)
+			}
+		case reflect.Struct:
+			setIfZero(tx, field, stmt.ReflectValue, value)
+		}
+	}
+}
+
+func setIfZero(tx *gorm.DB, field *schema.Field, rv reflect.Value, value interface{}) {
+	if _, zero := field.ValueOf(tx.Statement.Context, rv); zero {
+		_ = tx.AddError(field.Set(tx.Statement.Context, rv, value))
+	}
+}
+
+func (p *auditPlugin) beforeUpdate(tx *gorm.DB) {
+	stmt := tx.Statement
+	if tx.Error != nil {
+		return
+	}
+
+	// UpdateColumn 跳过 Hooks，不修改 updated_by
+	if userID, userName, ok := p.operatorOf(stmt.Context); ok && !stmt.SkipHooks && stmt.Schema != nil {
+		if stmt.Schema.LookUpField(_UpdatedBy) != nil {
+			stmt.SetColumn(_UpdatedBy, userID, true)
+		}
+		if stmt.Schema.LookUpField(_UpdatedByName) != nil {
+			stmt.SetColumn(_UpdatedByName, userName, true)
+		}
+	}
+
+	if !p.audited(stmt.Table) {
+		return
+	}
+	where, ok := p.where(stmt)
+	if !ok {
+		return
+	}
+
+	query := p.session(tx).Table(stmt.Table).Where(where)
+	if _, ok := stmt.ConnPool.(gorm.TxCommitter); ok {
+		// 锁住要更新的行，直到事务结束
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var before []map[string]interface{}
+	err := query.Find(&before).Error
+	if err != nil {
+		_ = tx.AddError(fmt.Errorf("audit select before update failed: %w", err))
+		return
+	}
+	tx.InstanceSet(_AuditBeforeKey, before)
+}
+
+func (p *auditPlugin) afterUpdate(tx *gorm.DB) {
+	stmt := tx.Statement
+	if tx.Error != nil || tx.RowsAffected == 0 {
+		return
+	}
+	v, ok := tx.InstanceGet(_AuditBeforeKey)
+	if !ok {
+		return
+	}
+	before := v.([]map[string]interface{})
+	if len(before) == 0 {
+		return
+	}
+
+	pk := primaryColumn(stmt)
+	ids := make([]interface{}, 0, len(before))
+	for _, row := range before {
+		ids = append(ids, row[pk])
+	}
+
+	var after []map[string]interface{}
+	err := p.session(tx).Table(stmt.Table).
+		Where(clause.IN{Column: clause.Column{Name: pk}, Values: ids}).
+		Find(&after).Error
+	if err != nil {
+		_ = tx.AddError(fmt.Errorf("audit select after update failed: %w", err))
+		return
+	}
+
+	afterByPK := make(map[string]map[string]interface{}, len(after))
+	for _, row := range after {
+		afterByPK[fmt.Sprint(row[pk])] = row
+	}
+
+	userID, userName, _ := p.operatorOf(stmt.Context)
+	now := time.Now()
+	records := make([]*AuditRecord, 0, len(before))
+	for _, old := range before {
+		id := fmt.Sprint(old[pk])
+		changes := diffRow(old, afterByPK[id])
+		if len(changes) == 0 {
+			continue
+		}
+		records = append(records, &AuditRecord{
+			Table:      stmt.Table,
+			PrimaryKey: id,
+			Action:     AuditActionUpdate,
+			Changes:    changes,
+			UserID:     userID,
+			UserName:   userName,
+			CreatedAt:  now,
+		})
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	if err = p.sink.Write(p.session(tx), records); err != nil {
+		_ = tx.AddError(fmt.Errorf("audit write failed: %w", err))
+	}
+}
+
+func (p *auditPlugin) audited(table string) bool {
+	if p.sink == nil || table == "" {
+		return false
+	}
+	return p.tables[table]
+}
+
+// session 与原语句共用连接（事务），不在事务中时从主库读取
+func (p *auditPlugin) session(tx *gorm.DB) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true, Context: UsePrimary(tx.Statement.Context)})
+}
+
+// where 取更新语句的 WHERE 条件，Model 带主键时加上主键条件
+func (p *auditPlugin) where(stmt *gorm.Statement) (clause.Expression, bool) {
+	var exprs []clause.Expression
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if w, ok := c.Expression.(clause.Where); ok {
+			exprs = append(exprs, w.Exprs...)
+		}
+	}
+
+	if stmt.Schema != nil && stmt.ReflectValue.Kind() == reflect.Struct {
+		for _, field := range stmt.Schema.PrimaryFields {
+			if value, zero := field.ValueOf(stmt.Context, stmt.ReflectValue); !zero {
+				exprs = append(exprs, clause.Eq{Column: clause.Column{Name: field.DBName}, Value: value})
+			}
+		}
+	}
+
+	// 没有条件的更新会被 gorm 拒绝，不需要记录
+	if len(exprs) == 0 {
+		return nil, false
+	}
+	return clause.And(exprs...), true
+}
+
+// primaryColumn 没有模型（Table 更新）时默认为 id
+func primaryColumn(stmt *gorm.Statement) string {
+	if stmt.Schema != nil && stmt.Schema.PrioritizedPrimaryField != nil {
+		return stmt.Schema.PrioritizedPrimaryField.DBName
+	}
+	return "id"
+}
+
+func diffRow(old, new map[string]interface{}) map[string]AuditChange {
+	changes := make(map[string]AuditChange)
+	for col, newValue := range new {
+		oldValue := old[col]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes[col] = AuditChange{Old: oldValue, New: newValue}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type stock struct {
+	Model
+	ProductID int
+	Stock     int
+}
+
+type operatorKey struct{}
+
+type testOperator struct {
+	id   int64
+	name string
+}
+
+func withOperator(ctx context.Context, id int64, name string) context.Context {
+	return context.WithValue(ctx, operatorKey{}, testOperator{id: id, name: name})
+}
+
+func operatorFromContext(ctx context.Context) (int64, string, bool) {
+	op, ok := ctx.Value(operatorKey{}).(testOperator)
+	return op.id, op.name, ok
+}
+
+type failSink struct{}
+
+func (failSink) Write(tx *gorm.DB, records []*AuditRecord) error {
+	return errors.New("sink down")
+}
+
+func newAuditRepo(t *testing.T, sink AuditSink, tables ...string) Repo {
+	repo, err := New(&DBConfig{
+		Driver:        DriverSQLite,
+		Name:          filepath.Join(t.TempDir(), "audit.db"),
+		ServerName:    "test",
+		AuditOperator: operatorFromContext,
+		AuditSink:     sink,
+		AuditTables:   tables,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = repo.DbClose()
+	})
+	assert.NoError(t, repo.GetDb().AutoMigrate(&stock{}, &goods{}))
+	assert.NoError(t, repo.GetDb().Table("audit_log").AutoMigrate(&AuditLog{}))
+	return repo
+}
+
+func TestAuditPlugin(t *testing.T) {
+	repo := newAuditRepo(t, NewTableSink("audit_log"), "stock")
+	ctx := withOperator(context.Background(), 7, "alice")
+
+	// 记录更新前数据时加行锁（sqlite 不支持，生成 SQL 时忽略）
+	var locked int
+	assert.NoError(t, repo.GetDb().Callback().Query().Before("gorm:query").Register("test:locking", func(tx *gorm.DB) {
+		if c, ok := tx.Statement.Clauses["FOR"]; ok {
+			if l, ok := c.Expression.(clause.Locking); ok && l.Strength == "UPDATE" {
+				locked++
+			}
+		}
+	}))
+
+	s := &stock{ProductID: 1, Stock: 10}
+	assert.NoError(t, repo.DB(ctx).Create(s).Error)
+	assert.Equal(t, int64(7), s.CreatedBy)
+	assert.Equal(t, "alice", s.CreatedByName)
+	assert.Equal(t, "alice", s.UpdatedByName)
+
+	bob := withOperator(context.Background(), 8, "bob")
+	assert.NoError(t, repo.DB(bob).Model(s).Updates(map[string]interface{}{"stock": 9}).Error)
+	assert.NoError(t, repo.DecrementIfPositive(bob, "stock", "stock", map[string]interface{}{"product_id": 1}, 2))
+
+	assert.Equal(t, 2, locked)
+
+	var got stock
+	assert.NoError(t, repo.DB(ctx).First(&got, s.ID).Error)
+	assert.Equal(t, 7, got.Stock)
+	assert.Equal(t, int64(7), got.CreatedBy)
+	assert.Equal(t, "bob", got.UpdatedByName)
+
+	var logs []*AuditLog
+	assert.NoError(t, repo.DB(ctx).Table("audit_log").Order("id").Find(&logs).Error)
+	if assert.Len(t, logs, 2) {
+		assert.Equal(t, "stock", logs[0].Target)
+		assert.Equal(t, "1", logs[0].PrimaryKey)
+		assert.Equal(t, int64(8), logs[0].UserID)
+
+		var changes map[string]AuditChange
+		assert.NoError(t, json.Unmarshal([]byte(logs[0].Changes), &changes))
+		assert.EqualValues(t, 10, changes["stock"].Old)
+		assert.EqualValues(t, 9, changes["stock"].New)
+		assert.Equal(t, "alice", changes["updated_by_name"].Old)
+		assert.Equal(t, "bob", changes["updated_by_name"].New)
+
+		assert.NoError(t, json.Unmarshal([]byte(logs[1].Changes), &changes))
+		assert.EqualValues(t, 7, changes["stock"].New)
+	}
+
+	// 不在 AuditTables 中的表不记录
+	assert.NoError(t, repo.DB(ctx).Create(&goods{ID: 1, Name: "phone"}).Error)
+	assert.NoError(t, repo.DB(ctx).Model(&goods{ID: 1}).Update("name", "tablet").Error)
+	var count int64
+	assert.NoError(t, repo.DB(ctx).Table("audit_log").Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	// 软删除
+	assert.NoError(t, repo.DB(ctx).Delete(&stock{}, s.ID).Error)
+	assert.ErrorIs(t, repo.DB(ctx).First(&stock{}, s.ID).Error, gorm.ErrRecordNotFound)
+	assert.NoError(t, repo.DB(ctx).Unscoped().First(&stock{}, s.ID).Error)
+}
+
+func TestAuditPlugin_SinkError(t *testing.T) {
+	repo := newAuditRepo(t, failSink{}, "stock")
+	ctx := context.Background()
+
+	s := &stock{ProductID: 1, Stock: 10}
+	assert.NoError(t, repo.DB(ctx).Create(s).Error)
+	assert.Zero(t, s.CreatedBy)
+
+	// 审计写入失败时更新回滚
+	assert.Error(t, repo.DB(ctx).Model(s).Update("stock", 1).Error)
+
+	var got stock
+	assert.NoError(t, repo.DB(ctx).First(&got, s.ID).Error)
+	assert.Equal(t, 10, got.Stock)
+}
+
+func TestAuditPlugin_NoTables(t *testing.T) {
+	repo := newAuditRepo(t, failSink{})
+	ctx := withOperator(context.Background(), 7, "alice")
+
+	// 没有配置 AuditTables 时只填充审计字段，不查询、不写入变更记录
+	s := &stock{ProductID: 1, Stock: 10}
+	assert.NoError(t, repo.DB(ctx).Create(s).Error)
+	assert.NoError(t, repo.DB(withOperator(ctx, 8, "bob")).Model(s).Update("stock", 1).Error)
+
+	var got stock
+	assert.NoError(t, repo.DB(ctx).First(&got, s.ID).Error)
+	assert.Equal(t, 1, got.Stock)
+	assert.Equal(t, int64(7), got.CreatedBy)
+	assert.Equal(t, "bob", got.UpdatedByName)
+}
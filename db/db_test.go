@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type product struct {
+	ID   int
+	Name string
+}
+
+func TestDSN(t *testing.T) {
+	t.Run("mysql", func(t *testing.T) {
+		dsn := mysqlDSN(&DBConfig{
+			User:   "root",
+			Pass:   "pass",
+			Name:   "seckill",
+			TLS:    "skip-verify",
+			Params: map[string]string{"timeout": "3s"},
+		}, "127.0.0.1:3306")
+		assert.Contains(t, dsn, "root:pass@tcp(127.0.0.1:3306)/seckill?")
+		assert.Contains(t, dsn, "charset=utf8mb4")
+		assert.Contains(t, dsn, "parseTime=true")
+		assert.Contains(t, dsn, "loc=Local")
+		assert.Contains(t, dsn, "tls=skip-verify")
+		assert.Contains(t, dsn, "timeout=3s")
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dsn, err := postgresDSN(&DBConfig{
+			User:   "postgres",
+			Pass:   "it's secret",
+			Name:   "seckill",
+			Params: map[string]string{"TimeZone": "Asia/Shanghai"},
+		}, "127.0.0.1")
+		assert.NoError(t, err)
+		assert.Equal(t, `TimeZone=Asia/Shanghai dbname=seckill host=127.0.0.1 password='it\'s secret' port=5432 sslmode=disable user=postgres`, dsn)
+
+		_, err = postgresDSN(&DBConfig{}, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		assert.Equal(t, "test.db", sqliteDSN(&DBConfig{Name: "test.db"}, ""))
+		assert.Equal(t, "replica.db", sqliteDSN(&DBConfig{Name: "test.db"}, "replica.db"))
+		assert.Equal(t, "file::memory:?cache=shared&_fk=1",
+			sqliteDSN(&DBConfig{Name: "file::memory:?cache=shared", Params: map[string]string{"_fk": "1"}}, ""))
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := dialector(&DBConfig{Driver: "oracle"}, "")
+		assert.Error(t, err)
+	})
+}
+
+// seedSQLite 创建 sqlite 文件并写入一条以文件区分的数据
+func seedSQLite(t *testing.T, path, name string) {
+	gdb, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, gdb.AutoMigrate(&product{}))
+	assert.NoError(t, gdb.Create(&product{ID: 1, Name: name}).Error)
+
+	sqlDB, _ := gdb.DB()
+	_ = sqlDB.Close()
+}
+
+func TestResolver(t *testing.T) {
+	dir := t.TempDir()
+	primary := filepath.Join(dir, "primary.db")
+	replica := filepath.Join(dir, "replica.db")
+	seedSQLite(t, primary, "primary")
+	seedSQLite(t, replica, "replica")
+
+	repo, err := New(&DBConfig{
+		Driver:     DriverSQLite,
+		Name:       primary,
+		Replicas:   []string{replica},
+		ServerName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.DbClose()
+
+	ctx := context.Background()
+	find := func(db *gorm.DB) string {
+		var p product
+		assert.NoError(t, db.First(&p, 1).Error)
+		return p.Name
+	}
+
+	assert.Equal(t, "replica", find(repo.DB(ctx)))
+	assert.Equal(t, "primary", find(repo.DB(UsePrimary(ctx))))
+
+	var count int64
+	assert.NoError(t, repo.DB(ctx).Raw("SELECT count(*) FROM product").Scan(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// 写入主库
+	assert.NoError(t, repo.DB(ctx).Create(&product{ID: 2, Name: "primary"}).Error)
+	assert.Error(t, repo.DB(ctx).First(&product{}, 2).Error)
+	assert.NoError(t, repo.DB(UsePrimary(ctx)).First(&product{}, 2).Error)
+
+	// 事务中读主库
+	err = repo.Transaction(ctx, func(ctx context.Context) error {
+		assert.Equal(t, "primary", find(repo.DB(ctx)))
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// 复用 Session 时读后写仍然写主库
+	q := repo.DB(ctx).Model(&product{}).Where("id = ?", 1)
+	var p product
+	assert.NoError(t, q.First(&p).Error)
+	assert.Equal(t, "replica", p.Name)
+	assert.NoError(t, q.Update("name", "updated").Error)
+	assert.Equal(t, "updated", find(repo.DB(UsePrimary(ctx))))
+	assert.Equal(t, "replica", find(repo.DB(ctx)))
+
+	// Migrator 读主库
+	type productV2 struct {
+		ID    int
+		Name  string
+		Stock int
+	}
+	migrator := repo.DB(ctx).Table("product").Migrator()
+	assert.NoError(t, migrator.AutoMigrate(&productV2{}))
+	assert.True(t, migrator.HasColumn(&productV2{}, "stock"))
+	assert.NoError(t, repo.DB(ctx).Table("product").AutoMigrate(&productV2{}))
+}
+
+func TestTransaction_Nested(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tx.db")
+	seedSQLite(t, path, "origin")
+
+	repo, err := New(&DBConfig{
+		Driver:     DriverSQLite,
+		Name:       path,
+		ServerName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.DbClose()
+
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	err = repo.Transaction(ctx, func(ctx context.Context) error {
+		assert.True(t, InTransaction(ctx))
+		if err := repo.DB(ctx).Create(&product{ID: 2, Name: "outer"}).Error; err != nil {
+			return err
+		}
+
+		// 内层回滚到 SAVEPOINT，不影响外层
+		err := repo.Transaction(ctx, func(ctx context.Context) error {
+			if err := repo.DB(ctx).Create(&product{ID: 3, Name: "inner"}).Error; err != nil {
+				return err
+			}
+			return errRollback
+		})
+		assert.ErrorIs(t, err, errRollback)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	var ids []int
+	assert.NoError(t, repo.DB(ctx).Model(&product{}).Order("id").Pluck("id", &ids).Error)
+	assert.Equal(t, []int{1, 2}, ids)
+}
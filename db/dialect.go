@@ -0,0 +1,139 @@
+package db
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// 支持的数据库驱动
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+const _DefaultPostgresPort = "5432"
+
+// dialector 根据 cfg.Driver 构建 gorm.Dialector，addr 为本次连接的节点地址（主库或副本）
+func dialector(cfg *DBConfig, addr string) (gorm.Dialector, error) {
+	switch cfg.driver() {
+	case DriverMySQL:
+		return mysql.Open(mysqlDSN(cfg, addr)), nil
+	case DriverPostgres:
+		dsn, err := postgresDSN(cfg, addr)
+		if err != nil {
+			return nil, err
+		}
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		return sqlite.Open(sqliteDSN(cfg, addr)), nil
+	default:
+		return nil, fmt.Errorf("unsupported db driver: %s ", cfg.Driver)
+	}
+}
+
+// mysqlDSN user:pass@tcp(addr)/name?charset=utf8mb4&parseTime=true&loc=Local
+// TLS 对应 go-sql-driver 的 tls 参数：true | false | skip-verify | preferred | 通过 mysql.RegisterTLSConfig 注册的名称
+func mysqlDSN(cfg *DBConfig, addr string) string {
+	mc := mysqlDriver.NewConfig()
+	mc.User = cfg.User
+	mc.Passwd = cfg.Pass
+	mc.Net = "tcp"
+	mc.Addr = addr
+	mc.DBName = cfg.Name
+	mc.ParseTime = true
+	mc.Loc = time.Local
+	mc.TLSConfig = cfg.TLS
+	mc.Params = map[string]string{
+		"charset": "utf8mb4",
+	}
+	for k, v := range cfg.Params {
+		mc.Params[k] = v
+	}
+	return mc.FormatDSN()
+}
+
+// postgresDSN host=127.0.0.1 port=5432 user=u password=p dbname=name sslmode=disable TimeZone=Local
+// TLS 对应 sslmode：disable | require | verify-ca | verify-full，默认 disable
+func postgresDSN(cfg *DBConfig, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// 未指定端口
+		host, port = addr, _DefaultPostgresPort
+	}
+	if host == "" {
+		return "", fmt.Errorf("postgres addr is empty ")
+	}
+
+	sslMode := cfg.TLS
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	params := map[string]string{
+		"host":     host,
+		"port":     port,
+		"user":     cfg.User,
+		"password": cfg.Pass,
+		"dbname":   cfg.Name,
+		"sslmode":  sslMode,
+		"TimeZone": time.Local.String(),
+	}
+	for k, v := range cfg.Params {
+		params[k] = v
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+postgresQuote(params[k]))
+	}
+	return strings.Join(pairs, " "), nil
+}
+
+// postgresQuote 值为空或包含空格、引号时需要用单引号包裹
+func postgresQuote(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+// sqliteDSN sqlite 没有地址，Name 为数据库文件路径（或 file::memory:）
+// 配置副本时 Replicas 为副本的文件路径。
+func sqliteDSN(cfg *DBConfig, addr string) string {
+	path := addr
+	if path == "" {
+		path = cfg.Name
+	}
+	if len(cfg.Params) == 0 {
+		return path
+	}
+
+	params := url.Values{}
+	for k, v := range cfg.Params {
+		params.Set(k, v)
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + params.Encode()
+}
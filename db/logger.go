@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/prometheus_helper"
+)
+
+// SQL 日志
+// 替换 gorm 默认的 logger，SQL 输出到 zap，并带上 ctx 中的 traceId / span_id，
+// 超过慢查询阈值的语句输出 Warn 日志并计入 gorm_slow_queries_total。
+
+const _DefaultSlowThreshold = 200 * time.Millisecond
+
+var (
+	// 单引号字符串，支持 '' 与 \' 转义
+	_RedactString = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)
+	// 独立的数字字面量，不匹配标识符中的数字（如 t1）
+	_RedactNumber = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+)
+
+type LoggerOption func(*gormLogger)
+
+// WithLogLevel 设置日志级别，默认 logger.Warn：只输出错误与慢查询；logger.Info 输出全部 SQL
+func WithLogLevel(level logger.LogLevel) LoggerOption {
+	return func(l *gormLogger) {
+		l.level = level
+	}
+}
+
+// WithSlowThreshold 设置慢查询阈值，默认 200ms，小于 0 时不记录慢查询
+func WithSlowThreshold(threshold time.Duration) LoggerOption {
+	return func(l *gormLogger) {
+		l.slowThreshold = threshold
+	}
+}
+
+// WithRedact 输出 SQL 时把字符串与数字字面量替换为 ?，避免手机号等敏感参数进入日志
+func WithRedact() LoggerOption {
+	return func(l *gormLogger) {
+		l.redact = true
+	}
+}
+
+type gormLogger struct {
+	zap           *zap.Logger
+	level         logger.LogLevel
+	slowThreshold time.Duration
+	redact        bool
+
+	slowQueries prometheus.Counter
+}
+
+var _ logger.Interface = (*gormLogger)(nil)
+
+// NewLogger 创建基于 zap 的 gorm logger
+func NewLogger(zl *zap.Logger, serverName string, options ...LoggerOption) logger.Interface {
+	l := &gormLogger{
+		zap:           zl,
+		level:         logger.Warn,
+		slowThreshold: _DefaultSlowThreshold,
+	}
+	for _, f := range options {
+		f(l)
+	}
+
+	l.slowQueries = prometheus_helper.RegisterOrExisting(prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gorm_slow_queries_total",
+		Help: "Number of sql statements slower than the slow threshold.",
+		ConstLabels: prometheus.Labels{
+			"system_name": serverName,
+		},
+	})).(prometheus.Counter)
+	return l
+}
+
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	nl := *l
+	nl.level = level
+	return &nl
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		l.with(ctx).Info(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		l.with(ctx).Warn(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		l.with(ctx).Error(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	cost := time.Since(begin)
+	slow := l.slowThreshold > 0 && cost > l.slowThreshold
+	if slow {
+		l.slowQueries.Inc()
+	}
+
+	switch {
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.with(ctx).Error("gorm sql error", l.fields(sql, rows, cost, zap.Error(err))...)
+	case slow && l.level >= logger.Warn:
+		sql, rows := fc()
+		l.with(ctx).Warn("gorm slow sql", l.fields(sql, rows, cost, zap.Duration("threshold", l.slowThreshold))...)
+	case l.level >= logger.Info:
+		sql, rows := fc()
+		l.with(ctx).Info("gorm sql", l.fields(sql, rows, cost)...)
+	}
+}
+
+func (l *gormLogger) fields(sql string, rows int64, cost time.Duration, extra ...zap.Field) []zap.Field {
+	if l.redact {
+		sql = redactSQL(sql)
+	}
+	fields := []zap.Field{
+		zap.String("sql", sql),
+		zap.Int64("rows", rows),
+		zap.Duration("cost", cost),
+		zap.String("source", utils.FileWithLineNum()),
+	}
+	return append(fields, extra...)
+}
+
+// with 带上 ctx 中的链路信息，字段名与 core.OpenTelemetry 一致
+func (l *gormLogger) with(ctx context.Context) *zap.Logger {
+	if ctx == nil {
+		return l.zap
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return l.zap
+	}
+	return l.zap.With(
+		zap.String("span_id", sc.SpanID().String()),
+		zap.String("traceId", sc.TraceID().String()),
+	)
+}
+
+func redactSQL(sql string) string {
+	sql = _RedactString.ReplaceAllString(sql, "?")
+	return _RedactNumber.ReplaceAllString(sql, "?")
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestRedactSQL(t *testing.T) {
+	assert.Equal(t,
+		"SELECT * FROM `user_t1` WHERE phone = ? AND name = ? AND age > ? LIMIT ?",
+		redactSQL("SELECT * FROM `user_t1` WHERE phone = '13800000000' AND name = 'o''neil' AND age > 18.5 LIMIT 1"),
+	)
+}
+
+func TestGormLogger_Trace(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	l := NewLogger(zap.New(core), "logger_test", WithSlowThreshold(time.Millisecond), WithRedact()).(*gormLogger)
+
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	fc := func() (string, int64) {
+		return "SELECT * FROM product WHERE id = 1", 1
+	}
+
+	// 默认 Warn 级别，普通 SQL 不输出
+	l.Trace(ctx, time.Now(), fc, nil)
+	assert.Equal(t, 0, logs.Len())
+
+	l.Trace(ctx, time.Now(), fc, gorm.ErrRecordNotFound)
+	assert.Equal(t, 0, logs.Len())
+
+	// 指标按 serverName 进程内共享，断言增量
+	before := testutil.ToFloat64(l.slowQueries)
+	l.Trace(ctx, time.Now().Add(-time.Second), fc, nil)
+	slow := logs.FilterMessage("gorm slow sql").All()
+	if assert.Len(t, slow, 1) {
+		fields := slow[0].ContextMap()
+		assert.Equal(t, "SELECT * FROM product WHERE id = ?", fields["sql"])
+		assert.Equal(t, traceID.String(), fields["traceId"])
+		assert.Equal(t, spanID.String(), fields["span_id"])
+	}
+	assert.Equal(t, before+1, testutil.ToFloat64(l.slowQueries))
+
+	l.Trace(ctx, time.Now(), fc, errors.New("bad connection"))
+	assert.Equal(t, 1, logs.FilterMessage("gorm sql error").Len())
+
+	l.LogMode(logger.Info).Trace(context.Background(), time.Now(), fc, nil)
+	assert.Equal(t, 1, logs.FilterMessage("gorm sql").Len())
+
+	l.LogMode(logger.Silent).Trace(ctx, time.Now().Add(-time.Second), fc, errors.New("ignored"))
+	assert.Equal(t, 3, logs.Len())
+}
+
+func TestNew_Logger(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	path := filepath.Join(t.TempDir(), "logger.db")
+	seedSQLite(t, path, "origin")
+
+	repo, err := New(&DBConfig{
+		Driver:     DriverSQLite,
+		Name:       path,
+		ServerName: "logger_test",
+		Logger:     zap.New(core),
+		LogLevel:   logger.Info,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.DbClose()
+
+	assert.NoError(t, repo.DB(context.Background()).First(&product{}, 1).Error)
+	assert.NotZero(t, logs.FilterMessage("gorm sql").Len())
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// prometheus metrics
+// 导出 sql.DBStats 连接池状态，主库与每个副本各一个 collector，以 node 区分
+
+// DBStatsClient describes a getter for sql.DBStats.
+type DBStatsClient interface {
+	Stats() sql.DBStats
+}
+
+var _ DBStatsClient = (*sql.DB)(nil)
+
+type statsCollector struct {
+	client DBStatsClient
+
+	maxOpenDesc           *prometheus.Desc
+	openDesc              *prometheus.Desc
+	inUseDesc             *prometheus.Desc
+	idleDesc              *prometheus.Desc
+	waitCountDesc         *prometheus.Desc
+	waitDurationDesc      *prometheus.Desc
+	maxIdleClosedDesc     *prometheus.Desc
+	maxIdleTimeClosedDesc *prometheus.Desc
+	maxLifetimeClosedDesc *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*statsCollector)(nil)
+
+// NewStatsCollector returns a new collector implements prometheus.Collector.
+func NewStatsCollector(client DBStatsClient, systemName, node string) prometheus.Collector {
+	labels := prometheus.Labels{
+		"system_name": systemName,
+		"node":        node,
+	}
+	return &statsCollector{
+		client: client,
+		maxOpenDesc: prometheus.NewDesc(statsFqName("max_open_connections"),
+			"Maximum number of open connections to the database.",
+			nil,
+			labels,
+		),
+		openDesc: prometheus.NewDesc(statsFqName("open_connections"),
+			"The number of established connections both in use and idle.",
+			nil,
+			labels,
+		),
+		inUseDesc: prometheus.NewDesc(statsFqName("in_use"),
+			"The number of connections currently in use.",
+			nil,
+			labels,
+		),
+		idleDesc: prometheus.NewDesc(statsFqName("idle"),
+			"The number of idle connections.",
+			nil,
+			labels,
+		),
+		waitCountDesc: prometheus.NewDesc(statsFqName("wait_count_total"),
+			"The total number of connections waited for.",
+			nil,
+			labels,
+		),
+		waitDurationDesc: prometheus.NewDesc(statsFqName("wait_duration_seconds_total"),
+			"The total time blocked waiting for a new connection.",
+			nil,
+			labels,
+		),
+		maxIdleClosedDesc: prometheus.NewDesc(statsFqName("max_idle_closed_total"),
+			"The total number of connections closed due to SetMaxIdleConns.",
+			nil,
+			labels,
+		),
+		maxIdleTimeClosedDesc: prometheus.NewDesc(statsFqName("max_idle_time_closed_total"),
+			"The total number of connections closed due to SetConnMaxIdleTime.",
+			nil,
+			labels,
+		),
+		maxLifetimeClosedDesc: prometheus.NewDesc(statsFqName("max_lifetime_closed_total"),
+			"The total number of connections closed due to SetConnMaxLifetime.",
+			nil,
+			labels,
+		),
+	}
+}
+
+func statsFqName(name string) string {
+	return "go_sql_stats_" + name
+}
+
+// Describe implements prometheus.Collector.
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpenDesc
+	ch <- c.openDesc
+	ch <- c.inUseDesc
+	ch <- c.idleDesc
+	ch <- c.waitCountDesc
+	ch <- c.waitDurationDesc
+	ch <- c.maxIdleClosedDesc
+	ch <- c.maxIdleTimeClosedDesc
+	ch <- c.maxLifetimeClosedDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.Stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpenDesc, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.openDesc, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUseDesc, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idleDesc, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCountDesc, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDurationDesc, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosedDesc, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxIdleTimeClosedDesc, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosedDesc, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
+
+type statsCollectors []prometheus.Collector
+
+// registerStats 注册主库与副本的连接池指标，DbClose 时注销
+func registerStats(cfg *DBConfig, primary *sql.DB, replicas []*sql.DB) statsCollectors {
+	collectors := make(statsCollectors, 0, len(replicas)+1)
+	collectors = append(collectors, NewStatsCollector(primary, cfg.ServerName, cfg.primaryName()))
+	for i, replica := range replicas {
+		collectors = append(collectors, NewStatsCollector(replica, cfg.ServerName, cfg.Replicas[i]))
+	}
+
+	registered := collectors[:0]
+	for _, c := range collectors {
+		// 同一节点重复创建时忽略，保留已注册的 collector
+		if err := prometheus.Register(c); err == nil {
+			registered = append(registered, c)
+		}
+	}
+	return registered
+}
+
+func (s statsCollectors) unregister() {
+	for _, c := range s {
+		prometheus.Unregister(c)
+	}
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsCollector(t *testing.T) {
+	dir := t.TempDir()
+	primary := filepath.Join(dir, "primary.db")
+	replica := filepath.Join(dir, "replica.db")
+
+	repo, err := New(&DBConfig{
+		Driver:      DriverSQLite,
+		Name:        primary,
+		Replicas:    []string{replica},
+		MaxOpenConn: 5,
+		ServerName:  "stats_test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, repo.Ping(ctx))
+
+	collectors := repo.(*dbRepo).collectors
+	if assert.Len(t, collectors, 2) {
+		assert.Equal(t, 9, testutil.CollectAndCount(collectors[0]))
+		expected := fmt.Sprintf(`
+# HELP go_sql_stats_max_open_connections Maximum number of open connections to the database.
+# TYPE go_sql_stats_max_open_connections gauge
+go_sql_stats_max_open_connections{node=%q,system_name="stats_test"} 5
+`, primary)
+		assert.NoError(t, testutil.CollectAndCompare(collectors[0], strings.NewReader(expected), "go_sql_stats_max_open_connections"))
+	}
+
+	assert.NoError(t, repo.DbClose())
+	assert.Error(t, repo.Ping(ctx))
+
+	// 注销后同一节点可以再次注册
+	repo, err = New(&DBConfig{Driver: DriverSQLite, Name: primary, ServerName: "stats_test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.DbClose()
+	assert.Len(t, repo.(*dbRepo).collectors, 1)
+}
@@ -0,0 +1,495 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 数据库迁移
+// 迁移按 Version 升序执行，已执行的记录在 schema_migrations 表中。
+// 迁移可以用 Go 函数注册，也可以从 .sql 文件加载（支持 embed）：
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	m := db.NewMigrator(repo.GetDb())
+//	if err := m.LoadFS(migrations, "migrations"); err != nil { ... }
+//	if err := m.Up(ctx); err != nil { ... }
+//
+// 文件名格式：<version>_<name>.up.sql / <version>_<name>.down.sql，如 20220601120000_create_order.up.sql
+// 一个文件中可以包含多条语句，每条语句以行尾的 ; 结束。
+//
+// 已执行的 SQL 迁移被修改后 checksum 不一致，Up 会拒绝执行并返回 ErrChecksumMismatch。
+// 多副本同时启动时通过数据库锁（mysql GET_LOCK / postgres pg_advisory_lock）保证只有一个副本执行迁移。
+// 配置了读写分离时，迁移的所有查询都在主库上执行。
+//
+// 每个迁移在一个事务中执行，但 mysql 的 DDL 会隐式提交事务，包含 DDL 的迁移失败时无法整体回滚，
+// 已执行的语句需要手动处理；postgres 的 DDL 支持事务，失败时整体回滚。
+
+const (
+	_DefaultMigrationTable = "schema_migrations"
+	_DefaultLockTimeout    = time.Minute
+)
+
+var (
+	// ErrChecksumMismatch 已执行的迁移内容被修改
+	ErrChecksumMismatch = errors.New("migration checksum mismatch. ")
+	// ErrMigrationLocked 获取迁移锁超时，其它副本正在执行迁移
+	ErrMigrationLocked = errors.New("migration is locked by another process. ")
+)
+
+var _MigrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration 一次迁移，Up/Down 与 UpSQL/DownSQL 二选一
+type Migration struct {
+	Version int64
+	Name    string
+
+	Up   func(ctx context.Context, tx *gorm.DB) error
+	Down func(ctx context.Context, tx *gorm.DB) error
+
+	UpSQL   string
+	DownSQL string
+}
+
+// checksum 只对 SQL 迁移计算，Go 迁移无法检测内容变化
+func (m *Migration) checksum() string {
+	if m.UpSQL == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(m.UpSQL))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *Migration) up(ctx context.Context, tx *gorm.DB) error {
+	if m.Up != nil {
+		return m.Up(ctx, tx)
+	}
+	return execSQL(tx, m.UpSQL)
+}
+
+func (m *Migration) down(ctx context.Context, tx *gorm.DB) error {
+	if m.Down != nil {
+		return m.Down(ctx, tx)
+	}
+	if m.DownSQL == "" {
+		return fmt.Errorf("migration %d_%s has no down ", m.Version, m.Name)
+	}
+	return execSQL(tx, m.DownSQL)
+}
+
+// execSQL 按行尾的 ; 拆分语句逐条执行，mysql 默认不支持一次执行多条语句
+func execSQL(tx *gorm.DB, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func splitStatements(script string) []string {
+	var (
+		stmts []string
+		buf   strings.Builder
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if buf.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(buf.String()))
+			buf.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(buf.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
+
+var (
+	globalMigrationsMu sync.Mutex
+	globalMigrations   []*Migration
+)
+
+// AddMigration 注册 Go 迁移，一般在 init 中调用，NewMigrator 创建时加载
+func AddMigration(m *Migration) {
+	globalMigrationsMu.Lock()
+	defer globalMigrationsMu.Unlock()
+	globalMigrations = append(globalMigrations, m)
+}
+
+type MigrateOption func(*Migrator)
+
+// WithMigrationTable 设置迁移记录表名，默认 schema_migrations
+func WithMigrationTable(table string) MigrateOption {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithLockTimeout 设置等待迁移锁的超时时间，默认 1 分钟
+func WithLockTimeout(timeout time.Duration) MigrateOption {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+type Migrator struct {
+	db          *gorm.DB
+	table       string
+	lockTimeout time.Duration
+	migrations  map[int64]*Migration
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Drift 已执行但内容被修改
+	Drift bool
+	// Missing 已执行但本地没有对应的迁移
+	Missing bool
+}
+
+type schemaMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	Checksum  string `gorm:"size:64"`
+	AppliedAt time.Time
+}
+
+// NewMigrator 创建迁移器，会加载 AddMigration 注册的迁移
+func NewMigrator(db *gorm.DB, options ...MigrateOption) *Migrator {
+	m := &Migrator{
+		db:          db,
+		table:       _DefaultMigrationTable,
+		lockTimeout: _DefaultLockTimeout,
+		migrations:  make(map[int64]*Migration),
+	}
+	for _, f := range options {
+		f(m)
+	}
+
+	globalMigrationsMu.Lock()
+	defer globalMigrationsMu.Unlock()
+	for _, gm := range globalMigrations {
+		// 全局迁移在 init 中注册，重复属于编码错误
+		if err := m.Add(gm); err != nil {
+			panic(err)
+		}
+	}
+	return m
+}
+
+// Add 添加迁移，Version 不能重复
+func (m *Migrator) Add(migrations ...*Migration) error {
+	for _, mg := range migrations {
+		if mg.Version <= 0 {
+			return fmt.Errorf("migration %s version must be positive ", mg.Name)
+		}
+		if mg.Up == nil && mg.UpSQL == "" {
+			return fmt.Errorf("migration %d_%s has no up ", mg.Version, mg.Name)
+		}
+		if exist, ok := m.migrations[mg.Version]; ok {
+			return fmt.Errorf("duplicate migration version %d: %s and %s ", mg.Version, exist.Name, mg.Name)
+		}
+		m.migrations[mg.Version] = mg
+	}
+	return nil
+}
+
+// LoadFS 加载 dir 目录下的 .sql 迁移文件
+func (m *Migrator) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("read migration dir %s failed: %w", dir, err)
+	}
+
+	loaded := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := _MigrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("migration file %s: %w", entry.Name(), err)
+		}
+		raw, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("read migration file %s failed: %w", entry.Name(), err)
+		}
+
+		mg, ok := loaded[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: match[2]}
+			loaded[version] = mg
+		}
+		if mg.Name != match[2] {
+			return fmt.Errorf("migration version %d has different names: %s and %s ", version, mg.Name, match[2])
+		}
+		if match[3] == "up" {
+			mg.UpSQL = string(raw)
+		} else {
+			mg.DownSQL = string(raw)
+		}
+	}
+
+	for _, mg := range loaded {
+		if err := m.Add(mg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Up 执行所有未执行的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		if err = m.verify(applied); err != nil {
+			return err
+		}
+
+		for _, mg := range m.sorted() {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			err = m.session(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := mg.up(ctx, tx); err != nil {
+					return err
+				}
+				return tx.Table(m.table).Create(&schemaMigration{
+					Version:   mg.Version,
+					Name:      mg.Name,
+					Checksum:  mg.checksum(),
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s up failed: %w", mg.Version, mg.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Down 按版本倒序回滚最近 steps 个已执行的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for i := 0; i < steps && i < len(versions); i++ {
+			mg, ok := m.migrations[versions[i]]
+			if !ok {
+				return fmt.Errorf("migration %d_%s not found ", versions[i], applied[versions[i]].Name)
+			}
+			err = m.session(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := mg.down(ctx, tx); err != nil {
+					return err
+				}
+				return tx.Table(m.table).Delete(&schemaMigration{}, mg.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s down failed: %w", mg.Version, mg.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Status 返回所有迁移的状态，按版本升序
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mg := range m.sorted() {
+		status := MigrationStatus{Version: mg.Version, Name: mg.Name}
+		if record, ok := applied[mg.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Drift = record.Checksum != mg.checksum()
+		}
+		result = append(result, status)
+	}
+	for v, record := range applied {
+		if _, ok := m.migrations[v]; !ok {
+			appliedAt := record.AppliedAt
+			result = append(result, MigrationStatus{
+				Version:   v,
+				Name:      record.Name,
+				Applied:   true,
+				AppliedAt: &appliedAt,
+				Missing:   true,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+func (m *Migrator) sorted() []*Migration {
+	list := make([]*Migration, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		list = append(list, mg)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+// session 迁移的查询都需要读主库，避免从库延迟读到旧的迁移记录
+func (m *Migrator) session(ctx context.Context) *gorm.DB {
+	return m.db.WithContext(UsePrimary(ctx))
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]*schemaMigration, error) {
+	db := m.session(ctx)
+	if err := db.Table(m.table).AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, fmt.Errorf("create table %s failed: %w", m.table, err)
+	}
+
+	var records []*schemaMigration
+	if err := db.Table(m.table).Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]*schemaMigration, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+func (m *Migrator) verify(applied map[int64]*schemaMigration) error {
+	for v, record := range applied {
+		mg, ok := m.migrations[v]
+		if !ok {
+			continue
+		}
+		if record.Checksum != mg.checksum() {
+			return fmt.Errorf("%w version: %d name: %s", ErrChecksumMismatch, v, mg.Name)
+		}
+	}
+	return nil
+}
+
+// withLock 在专用连接上持有会话级的锁，fn 执行完后释放
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	var acquire, release string
+	switch m.db.Dialector.Name() {
+	case DriverMySQL:
+		acquire, release = "SELECT GET_LOCK(?, ?)", "SELECT RELEASE_LOCK(?)"
+	case DriverPostgres:
+		acquire, release = "SELECT pg_try_advisory_lock(?)", "SELECT pg_advisory_unlock(?)"
+	default:
+		// sqlite 为单机文件，不需要分布式锁
+		return fn()
+	}
+
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	key := m.lockKey()
+	if err = m.acquire(ctx, conn, acquire, key); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), release, key)
+	}()
+
+	return fn()
+}
+
+func (m *Migrator) acquire(ctx context.Context, conn *sql.Conn, query string, key interface{}) error {
+	// mysql GET_LOCK 自带超时
+	if m.db.Dialector.Name() == DriverMySQL {
+		var ok sql.NullInt64
+		err := conn.QueryRowContext(ctx, query, key, int(m.lockTimeout.Seconds())).Scan(&ok)
+		if err != nil {
+			return fmt.Errorf("acquire migration lock failed: %w", err)
+		}
+		if ok.Int64 != 1 {
+			return ErrMigrationLocked
+		}
+		return nil
+	}
+
+	// postgres 轮询 pg_try_advisory_lock
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		var ok bool
+		if err := conn.QueryRowContext(ctx, query, key).Scan(&ok); err != nil {
+			return fmt.Errorf("acquire migration lock failed: %w", err)
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrMigrationLocked
+		}
+
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// lockKey mysql 使用字符串，postgres 使用 bigint
+func (m *Migrator) lockKey() interface{} {
+	name := "migrate:" + m.table
+	if m.db.Dialector.Name() == DriverMySQL {
+		return name
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
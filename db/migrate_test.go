@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newMigrateDB(t *testing.T) *gorm.DB {
+	repo, err := New(&DBConfig{
+		Driver:     DriverSQLite,
+		Name:       filepath.Join(t.TempDir(), "migrate.db"),
+		ServerName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = repo.DbClose()
+	})
+	return repo.GetDb()
+}
+
+func migrationFS() fstest.MapFS {
+	return fstest.MapFS{
+		"migrations/1_create_order.up.sql": {Data: []byte(`
+-- 订单表
+CREATE TABLE orders (
+	id INTEGER PRIMARY KEY,
+	note TEXT DEFAULT 'a;b'
+);
+CREATE INDEX idx_orders_note ON orders (note);
+`)},
+		"migrations/1_create_order.down.sql": {Data: []byte("DROP TABLE orders;")},
+		"migrations/README.md":               {Data: []byte("ignored")},
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	stmts := splitStatements(string(migrationFS()["migrations/1_create_order.up.sql"].Data))
+	assert.Len(t, stmts, 2)
+	assert.Contains(t, stmts[0], "DEFAULT 'a;b'")
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	gdb := newMigrateDB(t)
+
+	m := NewMigrator(gdb)
+	assert.NoError(t, m.LoadFS(migrationFS(), "migrations"))
+	assert.NoError(t, m.Add(&Migration{
+		Version: 2,
+		Name:    "seed_order",
+		Up: func(ctx context.Context, tx *gorm.DB) error {
+			return tx.Exec("INSERT INTO orders (id) VALUES (1)").Error
+		},
+		Down: func(ctx context.Context, tx *gorm.DB) error {
+			return tx.Exec("DELETE FROM orders").Error
+		},
+	}))
+	assert.Error(t, m.Add(&Migration{Version: 2, Name: "dup", UpSQL: "SELECT 1;"}))
+
+	assert.NoError(t, m.Up(ctx))
+	// 重复执行无副作用
+	assert.NoError(t, m.Up(ctx))
+
+	var count int64
+	assert.NoError(t, gdb.Table("orders").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	status, err := m.Status(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, status, 2) {
+		assert.True(t, status[0].Applied)
+		assert.True(t, status[1].Applied)
+		assert.False(t, status[0].Drift)
+	}
+
+	// 回滚最近一次
+	assert.NoError(t, m.Down(ctx, 1))
+	assert.NoError(t, gdb.Table("orders").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	status, err = m.Status(ctx)
+	assert.NoError(t, err)
+	assert.False(t, status[1].Applied)
+
+	// 全部回滚
+	assert.NoError(t, m.Down(ctx, 10))
+	assert.False(t, gdb.Migrator().HasTable("orders"))
+}
+
+func TestMigrator_Drift(t *testing.T) {
+	ctx := context.Background()
+	gdb := newMigrateDB(t)
+
+	m := NewMigrator(gdb)
+	assert.NoError(t, m.LoadFS(migrationFS(), "migrations"))
+	assert.NoError(t, m.Up(ctx))
+
+	// 已执行的迁移被修改
+	changed := migrationFS()
+	changed["migrations/1_create_order.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE orders (id INTEGER PRIMARY KEY);")}
+	changed["migrations/3_add_user.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")}
+
+	m = NewMigrator(gdb)
+	assert.NoError(t, m.LoadFS(changed, "migrations"))
+	assert.ErrorIs(t, m.Up(ctx), ErrChecksumMismatch)
+	assert.False(t, gdb.Migrator().HasTable("users"))
+
+	status, err := m.Status(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, status, 2) {
+		assert.True(t, status[0].Drift)
+		assert.False(t, status[1].Applied)
+	}
+
+	// 本地缺少已执行的迁移
+	m = NewMigrator(gdb)
+	status, err = m.Status(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, status, 1) {
+		assert.True(t, status[0].Missing)
+	}
+}
+
+func TestMigrator_Replicas(t *testing.T) {
+	dir := t.TempDir()
+	primary := filepath.Join(dir, "primary.db")
+	replica := filepath.Join(dir, "replica.db")
+	seedSQLite(t, primary, "primary")
+	seedSQLite(t, replica, "replica")
+
+	repo, err := New(&DBConfig{
+		Driver:     DriverSQLite,
+		Name:       primary,
+		Replicas:   []string{replica},
+		ServerName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.DbClose()
+
+	// 迁移记录只在主库，读从库会重复执行迁移
+	ctx := context.Background()
+	m := NewMigrator(repo.GetDb())
+	assert.NoError(t, m.LoadFS(migrationFS(), "migrations"))
+	assert.NoError(t, m.Up(ctx))
+	assert.NoError(t, m.Up(ctx))
+
+	status, err := m.Status(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, status, 1) {
+		assert.True(t, status[0].Applied)
+	}
+}
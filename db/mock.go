@@ -1,9 +1,13 @@
 package db
 
 import (
+	"context"
+
 	"gorm.io/gorm"
 )
 
+var _ Repo = (*MockRepo)(nil)
+
 type MockRepo struct {
 	GDB *gorm.DB
 }
@@ -15,3 +19,27 @@ func (m MockRepo) GetDb() *gorm.DB {
 func (m MockRepo) DbClose() error {
 	return nil
 }
+
+func (m MockRepo) DB(ctx context.Context) *gorm.DB {
+	return dbWithContext(m.GDB, ctx)
+}
+
+func (m MockRepo) Transaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	return transaction(m.GDB, ctx, fn, opts...)
+}
+
+func (m MockRepo) UpdateWithVersion(ctx context.Context, model interface{}, updates map[string]interface{}, opts ...RetryOption) error {
+	return updateWithVersion(m.GDB, ctx, model, updates, opts...)
+}
+
+func (m MockRepo) DecrementIfPositive(ctx context.Context, table, column string, where map[string]interface{}, n int64, opts ...RetryOption) error {
+	return decrementIfPositive(m.GDB, ctx, table, column, where, n, opts...)
+}
+
+func (m MockRepo) Ping(ctx context.Context) error {
+	sqlDB, err := m.GDB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
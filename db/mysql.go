@@ -1,11 +1,14 @@
 package db
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
-	"gorm.io/driver/mysql"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 	"gorm.io/plugin/prometheus"
 )
@@ -15,10 +18,28 @@ var _ Repo = (*dbRepo)(nil)
 type Repo interface {
 	GetDb() *gorm.DB
 	DbClose() error
+
+	// DB 获取 ctx 中的事务，不在事务中时返回 GetDb()，均已设置 ctx
+	DB(ctx context.Context) *gorm.DB
+	// Transaction 开启事务并放入 ctx，fn 中通过 DB(ctx) 获取事务
+	// 已在事务中时使用 SAVEPOINT 嵌套
+	Transaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error
+
+	// UpdateWithVersion 乐观锁更新，model 需包含 Version 字段，版本不匹配时返回 ErrConcurrentModification
+	// 在事务中时版本冲突不会重试，直接返回 ErrConcurrentModification，由调用方重试整个事务
+	UpdateWithVersion(ctx context.Context, model interface{}, updates map[string]interface{}, opts ...RetryOption) error
+	// DecrementIfPositive 条件扣减 column，值不足时返回 ErrInsufficient
+	DecrementIfPositive(ctx context.Context, table, column string, where map[string]interface{}, n int64, opts ...RetryOption) error
+
+	// Ping 检查主库与所有副本的连接
+	Ping(ctx context.Context) error
 }
 
 type dbRepo struct {
-	Db *gorm.DB
+	Db           *gorm.DB
+	replicas     []*sql.DB
+	replicaAddrs []string
+	collectors   statsCollectors
 }
 
 type DBConfig struct {
@@ -30,17 +51,50 @@ type DBConfig struct {
 	MaxIdleConn     int
 	ConnMaxLifeTime time.Duration
 
+	// Driver 数据库驱动：mysql(默认) | postgres | sqlite
+	// sqlite 使用 Name 作为数据库文件路径，Addr 可为空
+	Driver string
+	// TLS mysql 为 tls 参数（true | skip-verify | preferred | 注册的名称），postgres 为 sslmode
+	TLS string
+	// Params 附加的 DSN 参数，覆盖默认值
+	Params map[string]string
+
+	// Replicas 只读副本地址，账号、库名与主库相同。
+	// 配置后 Query/Row 自动路由到副本，写操作、事务、UsePrimary(ctx) 使用主库。
+	Replicas      []string
+	ReplicaPolicy string // 副本负载均衡策略：random(默认) | round_robin
+
+	// Logger 设置后 SQL 日志输出到 zap，为空时使用 gorm 默认 logger
+	Logger        *zap.Logger
+	LogLevel      logger.LogLevel // 默认 logger.Warn：只输出错误与慢查询
+	SlowThreshold time.Duration   // 慢查询阈值，默认 200ms
+	RedactSQL     bool            // 日志中隐藏 SQL 参数
+
+	// AuditOperator 设置后创建、更新时填充 created_by / updated_by 等审计字段，如 core.Operator
+	AuditOperator AuditOperator
+	// AuditSink 设置后记录 AuditTables 中的表更新前后的字段变化，见 NewTableSink
+	AuditSink   AuditSink
+	AuditTables []string // 只记录这些表的变更，为空时不记录
+
 	ServerName string // 服务标识
 }
 
 func New(cfg *DBConfig) (Repo, error) {
-	db, err := dbConnect(cfg)
+	db, replicas, err := dbConnect(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
 
 	return &dbRepo{
-		Db: db,
+		Db:           db,
+		replicas:     replicas,
+		replicaAddrs: cfg.Replicas,
+		collectors:   registerStats(cfg, sqlDB, replicas),
 	}, nil
 }
 
@@ -48,7 +102,45 @@ func (d *dbRepo) GetDb() *gorm.DB {
 	return d.Db
 }
 
+func (d *dbRepo) DB(ctx context.Context) *gorm.DB {
+	return dbWithContext(d.Db, ctx)
+}
+
+func (d *dbRepo) Transaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	return transaction(d.Db, ctx, fn, opts...)
+}
+
+func (d *dbRepo) UpdateWithVersion(ctx context.Context, model interface{}, updates map[string]interface{}, opts ...RetryOption) error {
+	return updateWithVersion(d.Db, ctx, model, updates, opts...)
+}
+
+func (d *dbRepo) DecrementIfPositive(ctx context.Context, table, column string, where map[string]interface{}, n int64, opts ...RetryOption) error {
+	return decrementIfPositive(d.Db, ctx, table, column, where, n, opts...)
+}
+
+func (d *dbRepo) Ping(ctx context.Context) error {
+	sqlDB, err := d.Db.DB()
+	if err != nil {
+		return err
+	}
+	if err = sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping db primary failed: %w", err)
+	}
+
+	for i, replica := range d.replicas {
+		if err = replica.PingContext(ctx); err != nil {
+			return fmt.Errorf("ping db replica %s failed: %w", d.replicaAddrs[i], err)
+		}
+	}
+	return nil
+}
+
 func (d *dbRepo) DbClose() error {
+	d.collectors.unregister()
+	for _, replica := range d.replicas {
+		_ = replica.Close()
+	}
+
 	sqlDB, err := d.Db.DB()
 	if err != nil {
 		return err
@@ -56,56 +148,159 @@ func (d *dbRepo) DbClose() error {
 	return sqlDB.Close()
 }
 
-func dbConnect(cfg *DBConfig) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=%t&loc=%s",
-		cfg.User,
-		cfg.Pass,
-		cfg.Addr,
-		cfg.Name,
-		true,
-		"Local")
+func (cfg *DBConfig) driver() string {
+	if cfg.Driver == "" {
+		return DriverMySQL
+	}
+	return cfg.Driver
+}
+
+// primaryName 主库节点名称，sqlite 没有地址时使用库名
+func (cfg *DBConfig) primaryName() string {
+	if cfg.Addr == "" {
+		return cfg.Name
+	}
+	return cfg.Addr
+}
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+func dbConnect(cfg *DBConfig) (*gorm.DB, []*sql.DB, error) {
+	p, err := newPolicy(cfg.ReplicaPolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dial, err := dialector(cfg, cfg.Addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gormConfig := &gorm.Config{
 		NamingStrategy: schema.NamingStrategy{
 			SingularTable: true,
 		},
-	})
+	}
+	if cfg.Logger != nil {
+		gormConfig.Logger = newConfigLogger(cfg)
+	}
+
+	db, err := gorm.Open(dial, gormConfig)
 
 	if err != nil {
-		return nil, fmt.Errorf("[db connection failed] Database name: %s %w ", cfg.Name, err)
+		return nil, nil, fmt.Errorf("[db connection failed] Database name: %s %w ", cfg.Name, err)
+	}
+	if cfg.driver() == DriverMySQL {
+		db.Set("gorm:table_options", "CHARSET=utf8mb4")
 	}
-	db.Set("gorm:table_options", "CHARSET=utf8mb4")
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	setConnPool(sqlDB, cfg)
 
-	// 设置连接池 用于设置最大打开的连接数，默认值为0表示不限制.设置最大的连接数，可以避免并发太高导致连接mysql出现too many connections的错误。
-	sqlDB.SetMaxOpenConns(cfg.MaxOpenConn)
-
-	// 设置最大连接数 用于设置闲置的连接数.设置闲置的连接数则当开启的一个连接使用完成后可以放在池里等候下一次使用。
-	sqlDB.SetMaxIdleConns(cfg.MaxIdleConn)
+	replicas := make([]*sql.DB, 0, len(cfg.Replicas))
+	closeAll := func() {
+		for _, r := range replicas {
+			_ = r.Close()
+		}
+		_ = sqlDB.Close()
+	}
 
-	// 设置最大连接超时
-	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifeTime)
+	replicaNodes := make([]*node, 0, len(cfg.Replicas))
+	for _, addr := range cfg.Replicas {
+		replica, err := replicaConnect(cfg, addr)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		replicas = append(replicas, replica)
+		replicaNodes = append(replicaNodes, &node{name: addr, pool: replica})
+	}
 
 	// 使用插件
+	err = db.Use(newResolver(&node{name: cfg.primaryName(), pool: db.ConnPool}, replicaNodes, p))
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+
 	err = db.Use(NewPlugin(cfg.ServerName, WithDBName(cfg.Name)))
 	if err != nil {
-		return nil, err
+		closeAll()
+		return nil, nil, err
+	}
+
+	err = db.Use(NewAuditPlugin(
+		WithOperator(cfg.AuditOperator),
+		WithAuditSink(cfg.AuditSink),
+		WithAuditTables(cfg.AuditTables...),
+	))
+	if err != nil {
+		closeAll()
+		return nil, nil, err
 	}
 
+	var collectors []prometheus.MetricsCollector
+	if cfg.driver() == DriverMySQL {
+		// MySQL 状态变量只在 mysql 上可用
+		collectors = append(collectors,
+			&prometheus.MySQL{VariableNames: []string{"Threads_connected", "Slow_queries", "Table_locks_waited"}},
+		)
+	}
 	err = db.Use(prometheus.New(prometheus.Config{
 		DBName: cfg.ServerName, // `DBName` as metrics label
 		// RefreshInterval: 15,                          // refresh metrics interval (default 15 seconds)
-		MetricsCollector: []prometheus.MetricsCollector{
-			&prometheus.MySQL{VariableNames: []string{"Threads_connected", "Slow_queries", "Table_locks_waited"}},
-		},
+		MetricsCollector: collectors,
 	}))
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+
+	return db, replicas, nil
+}
+
+// replicaConnect 连接只读副本
+func replicaConnect(cfg *DBConfig, addr string) (*sql.DB, error) {
+	dial, err := dialector(cfg, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	replica, err := gorm.Open(dial, &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("[db replica connection failed] Database addr: %s %w ", addr, err)
+	}
+
+	sqlDB, err := replica.DB()
 	if err != nil {
 		return nil, err
 	}
+	setConnPool(sqlDB, cfg)
+	return sqlDB, nil
+}
 
-	return db, nil
+func newConfigLogger(cfg *DBConfig) logger.Interface {
+	var options []LoggerOption
+	if cfg.LogLevel != 0 {
+		options = append(options, WithLogLevel(cfg.LogLevel))
+	}
+	if cfg.SlowThreshold != 0 {
+		options = append(options, WithSlowThreshold(cfg.SlowThreshold))
+	}
+	if cfg.RedactSQL {
+		options = append(options, WithRedact())
+	}
+	return NewLogger(cfg.Logger, cfg.ServerName, options...)
+}
+
+func setConnPool(sqlDB *sql.DB, cfg *DBConfig) {
+	// 设置连接池 用于设置最大打开的连接数，默认值为0表示不限制.设置最大的连接数，可以避免并发太高导致连接mysql出现too many connections的错误。
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConn)
+
+	// 设置最大连接数 用于设置闲置的连接数.设置闲置的连接数则当开启的一个连接使用完成后可以放在池里等候下一次使用。
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConn)
+
+	// 设置最大连接超时
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifeTime)
 }
@@ -11,7 +11,10 @@ import (
 	"gorm.io/gorm"
 )
 
-var dbRowsAffected = attribute.Key("db.rows_affected")
+var (
+	dbRowsAffected = attribute.Key("db.rows_affected")
+	dbNode         = attribute.Key("db.node")
+)
 
 type Option func(p *otelPlugin)
 
@@ -132,6 +135,10 @@ func (p *otelPlugin) after() gormHookFunc {
 		if tx.Statement.RowsAffected != -1 {
 			attrs = append(attrs, dbRowsAffected.Int64(tx.Statement.RowsAffected))
 		}
+		// 读写分离时实际执行的节点
+		if node, ok := tx.InstanceGet(_NodeKey); ok {
+			attrs = append(attrs, dbNode.String(node.(string)))
+		}
 
 		span.SetAttributes(attrs...)
 		if tx.Error != nil {
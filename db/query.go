@@ -0,0 +1,203 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/page"
+	"github.com/HYY-yu/seckill.pkg/pkg/util"
+)
+
+// 分页与筛选
+// 把 page.PageRequest 的 Filter 按白名单转换为 WHERE 子句：
+//
+//	spec := db.FilterSpec{
+//		"name":        {Op: db.OpLike},
+//		"status":      {Op: db.OpIn},
+//		"create_time": {Op: db.OpRange},
+//		"user":        {Column: "user_id"},
+//	}
+//	pr.AddAllowSortField("id", "create_time")
+//
+//	var list []*Order
+//	p, err := db.FindPage(repo.DB(ctx).Model(&Order{}), pr, spec, &list)
+//
+// in / range 的值可以是切片，也可以是逗号分隔的字符串：status=1,2  create_time=2022-01-01,2022-02-01
+// range 任一端为空时表示不限制，如 create_time=,2022-02-01
+// like 的值按字面匹配，其中的 % 和 _ 会被转义
+
+type FilterOp string
+
+const (
+	OpEq    FilterOp = "eq"
+	OpLike  FilterOp = "like"
+	OpIn    FilterOp = "in"
+	OpRange FilterOp = "range"
+	OpGt    FilterOp = "gt"
+	OpGte   FilterOp = "gte"
+	OpLt    FilterOp = "lt"
+	OpLte   FilterOp = "lte"
+)
+
+// Filter 单个筛选字段，Column 为空时使用 Filter 的 key，Op 为空时使用 OpEq
+type Filter struct {
+	Column string
+	Op     FilterOp
+}
+
+// FilterSpec 筛选字段白名单，不在白名单中的 key 会被忽略
+type FilterSpec map[string]Filter
+
+// Paginate 分页与排序，排序字段需通过 pr.AddAllowSortField 加入白名单
+func Paginate(pr *page.PageRequest) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		limit, offset := pr.GetLimitAndOffset()
+		if limit > 0 {
+			tx = tx.Limit(limit)
+		}
+		if offset > 0 {
+			tx = tx.Offset(offset)
+		}
+		if sort, ok := pr.Sort(); ok {
+			tx = tx.Order(sort)
+		}
+		return tx
+	}
+}
+
+// ApplyFilter 按 spec 把 pr.Filter 转换为 WHERE 子句，值为空的字段会被忽略
+// 条件按 key 排序，相同的筛选生成相同的 SQL，便于命中 prepared statement 缓存
+func ApplyFilter(pr *page.PageRequest, spec FilterSpec) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		keys := make([]string, 0, len(pr.Filter))
+		for key := range pr.Filter {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value := pr.Filter[key]
+			f, ok := spec[key]
+			if !ok || isEmptyValue(value) {
+				continue
+			}
+			if f.Column == "" {
+				f.Column = key
+			}
+
+			exprs, err := f.exprs(value)
+			if err != nil {
+				_ = tx.AddError(fmt.Errorf("filter %s: %w", key, err))
+				return tx
+			}
+			if len(exprs) > 0 {
+				tx = tx.Where(clause.And(exprs...))
+			}
+		}
+		return tx
+	}
+}
+
+// FindPage 一次调用完成筛选、计数与分页查询，tx 需设置 Model，dest 为切片指针
+func FindPage(tx *gorm.DB, pr *page.PageRequest, spec FilterSpec, dest interface{}) (*page.Page, error) {
+	query := tx.Scopes(ApplyFilter(pr, spec)).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	if total > 0 {
+		if err := query.Scopes(Paginate(pr)).Find(dest).Error; err != nil {
+			return nil, err
+		}
+	}
+	return page.NewPage(total, dest), nil
+}
+
+func (f Filter) exprs(value interface{}) ([]clause.Expression, error) {
+	col := clause.Column{Name: f.Column}
+	switch f.Op {
+	case "", OpEq:
+		return []clause.Expression{clause.Eq{Column: col, Value: value}}, nil
+	case OpLike:
+		// mysql 与 postgres 默认的转义符是 \，sqlite 没有默认转义符，统一指定为 !
+		return []clause.Expression{clause.Expr{
+			SQL:  "? LIKE ? ESCAPE '!'",
+			Vars: []interface{}{col, util.WrapSqlLike(escapeLike(cast.ToString(value)))},
+		}}, nil
+	case OpIn:
+		return []clause.Expression{clause.IN{Column: col, Values: splitValues(value)}}, nil
+	case OpGt:
+		return []clause.Expression{clause.Gt{Column: col, Value: value}}, nil
+	case OpGte:
+		return []clause.Expression{clause.Gte{Column: col, Value: value}}, nil
+	case OpLt:
+		return []clause.Expression{clause.Lt{Column: col, Value: value}}, nil
+	case OpLte:
+		return []clause.Expression{clause.Lte{Column: col, Value: value}}, nil
+	case OpRange:
+		values := splitValues(value)
+		if len(values) != 2 {
+			return nil, fmt.Errorf("range needs 2 values, got %d ", len(values))
+		}
+		var exprs []clause.Expression
+		if !isEmptyValue(values[0]) {
+			exprs = append(exprs, clause.Gte{Column: col, Value: values[0]})
+		}
+		if !isEmptyValue(values[1]) {
+			exprs = append(exprs, clause.Lte{Column: col, Value: values[1]})
+		}
+		return exprs, nil
+	default:
+		return nil, fmt.Errorf("unknown filter op %s ", f.Op)
+	}
+}
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// escapeLike 转义 LIKE 的通配符，转义符为 !
+func escapeLike(v string) string {
+	return likeEscaper.Replace(v)
+}
+
+// splitValues 切片原样返回，字符串按逗号拆分
+func splitValues(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		values := make([]interface{}, len(v))
+		for i := range v {
+			values[i] = v[i]
+		}
+		return values
+	case string:
+		parts := strings.Split(v, ",")
+		values := make([]interface{}, len(parts))
+		for i := range parts {
+			values[i] = strings.TrimSpace(parts[i])
+		}
+		return values
+	default:
+		return []interface{}{value}
+	}
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	case []string:
+		return len(v) == 0
+	}
+	return false
+}
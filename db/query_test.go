@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/page"
+)
+
+type goods struct {
+	ID     int
+	Name   string
+	Status int
+	Price  int
+}
+
+func newQueryRepo(t *testing.T) Repo {
+	repo, err := New(&DBConfig{
+		Driver:     DriverSQLite,
+		Name:       filepath.Join(t.TempDir(), "query.db"),
+		ServerName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = repo.DbClose()
+	})
+
+	assert.NoError(t, repo.GetDb().AutoMigrate(&goods{}))
+	for i := 1; i <= 20; i++ {
+		assert.NoError(t, repo.GetDb().Create(&goods{
+			ID:     i,
+			Name:   fmt.Sprintf("phone-%02d", i),
+			Status: i % 3,
+			Price:  i * 100,
+		}).Error)
+	}
+	return repo
+}
+
+func TestFindPage(t *testing.T) {
+	ctx := context.Background()
+	repo := newQueryRepo(t)
+
+	spec := FilterSpec{
+		"name":   {Op: OpLike},
+		"status": {Op: OpIn},
+		"price":  {Op: OpRange},
+		"min_id": {Column: "id", Op: OpGt},
+	}
+
+	t.Run("filter and page", func(t *testing.T) {
+		pr := page.NewPageRequest(2, 3, "price-", map[string]interface{}{
+			"name":   "phone",
+			"status": "1,2",
+			"price":  "500,",
+			"min_id": 5,
+			"id":     1, // 不在白名单中，忽略
+		})
+		pr.AddAllowSortField("price")
+
+		var list []*goods
+		p, err := FindPage(repo.DB(ctx).Model(&goods{}), pr, spec, &list)
+		assert.NoError(t, err)
+		// id 6..20 中 status 为 1、2 的共 10 条
+		assert.Equal(t, int64(10), p.TotalCount)
+		if assert.Len(t, list, 3) {
+			assert.Equal(t, []int{16, 14, 13}, []int{list[0].ID, list[1].ID, list[2].ID})
+		}
+	})
+
+	t.Run("range upper bound", func(t *testing.T) {
+		pr := page.NewPageRequest(1, 10, "", map[string]interface{}{
+			"price": []interface{}{"", 300},
+			"name":  "",
+		})
+
+		var list []*goods
+		p, err := FindPage(repo.DB(ctx).Model(&goods{}), pr, spec, &list)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), p.TotalCount)
+		assert.Len(t, list, 3)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		pr := page.NewPageRequest(1, 10, "", map[string]interface{}{"name": "tablet"})
+
+		var list []*goods
+		p, err := FindPage(repo.DB(ctx).Model(&goods{}), pr, spec, &list)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), p.TotalCount)
+		assert.Empty(t, list)
+	})
+
+	t.Run("bad range", func(t *testing.T) {
+		pr := page.NewPageRequest(1, 10, "", map[string]interface{}{"price": "1,2,3"})
+
+		var list []*goods
+		_, err := FindPage(repo.DB(ctx).Model(&goods{}), pr, spec, &list)
+		assert.Error(t, err)
+	})
+
+	t.Run("like escape", func(t *testing.T) {
+		// % 和 _ 按字面匹配
+		for _, name := range []string{"%", "phone_", "e-1%"} {
+			pr := page.NewPageRequest(1, 10, "", map[string]interface{}{"name": name})
+
+			var list []*goods
+			p, err := FindPage(repo.DB(ctx).Model(&goods{}), pr, spec, &list)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(0), p.TotalCount, name)
+		}
+	})
+
+	t.Run("stable sql", func(t *testing.T) {
+		pr := page.NewPageRequest(1, 10, "", map[string]interface{}{
+			"status": 1,
+			"name":   "phone",
+			"min_id": 5,
+			"price":  "100,200",
+		})
+		query := func(tx *gorm.DB) *gorm.DB {
+			var list []*goods
+			return tx.Model(&goods{}).Scopes(ApplyFilter(pr, spec)).Find(&list)
+		}
+
+		sql := repo.GetDb().ToSQL(query)
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, sql, repo.GetDb().ToSQL(query))
+		}
+		assert.Contains(t, sql, "`id` > 5 AND `name` LIKE \"%phone%\" ESCAPE '!' AND (`price` >= \"100\" AND `price` <= \"200\") AND `status` = 1")
+	})
+
+	t.Run("sort not allowed", func(t *testing.T) {
+		pr := page.NewPageRequest(1, 1, "name; DROP TABLE goods", nil)
+
+		var list []*goods
+		assert.NoError(t, repo.DB(ctx).Scopes(Paginate(pr)).Find(&list).Error)
+		assert.Len(t, list, 1)
+		assert.True(t, repo.GetDb().Migrator().HasTable(&goods{}))
+	})
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 多数据源注册
+// 服务同时访问多个库时（如：商品库、订单库），按名称注册后在任意位置获取。
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Repo)
+)
+
+// Register 连接数据库并以 name 注册
+func Register(name string, cfg *DBConfig) (Repo, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		return nil, fmt.Errorf("db %s has already registered ", name)
+	}
+
+	repo, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	registry[name] = repo
+	return repo, nil
+}
+
+// Get 获取已注册的数据库
+func Get(name string) Repo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	repo, ok := registry[name]
+	if !ok {
+		panic(fmt.Sprintf("db %s is not registered, you must run Register first. ", name))
+	}
+	return repo
+}
+
+// CloseAll 关闭并移除所有已注册的数据库
+func CloseAll() error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	var firstErr error
+	for name, repo := range registry {
+		if err := repo.DbClose(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("close db %s failed: %w", name, err)
+		}
+		delete(registry, name)
+	}
+	return firstErr
+}
@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 读写分离
+// Query / Row 回调自动路由到只读副本，以下情况仍使用主库：
+// 1. 处于事务中
+// 2. ctx 经过 UsePrimary 标记（写后读）
+// 3. 带 FOR UPDATE / FOR SHARE 等锁定子句
+// 4. Raw SQL 不是 SELECT 语句
+// Create / Update / Delete / Raw 回调始终使用主库。
+// Migrator 的查询（HasTable、HasColumn、ColumnTypes 等）也使用主库，避免副本延迟导致重复执行 DDL。
+
+const (
+	// PolicyRandom 随机选择副本
+	PolicyRandom = "random"
+	// PolicyRoundRobin 轮询副本
+	PolicyRoundRobin = "round_robin"
+)
+
+// _NodeKey 记录本次语句实际使用的节点，供 otel 插件打标签
+const _NodeKey = "db:node"
+
+type primaryKey struct{}
+
+// UsePrimary 标记 ctx 强制使用主库，用于写后立即读的场景
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func isUsePrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	v, _ := ctx.Value(primaryKey{}).(bool)
+	return v
+}
+
+type node struct {
+	name string
+	pool gorm.ConnPool
+}
+
+type policy interface {
+	resolve(replicas []*node) *node
+}
+
+type randomPolicy struct{}
+
+func (randomPolicy) resolve(replicas []*node) *node {
+	return replicas[rand.Intn(len(replicas))]
+}
+
+type roundRobinPolicy struct {
+	next uint64
+}
+
+func (p *roundRobinPolicy) resolve(replicas []*node) *node {
+	n := atomic.AddUint64(&p.next, 1)
+	return replicas[(n-1)%uint64(len(replicas))]
+}
+
+func newPolicy(name string) (policy, error) {
+	switch name {
+	case "", PolicyRandom:
+		return randomPolicy{}, nil
+	case PolicyRoundRobin:
+		return &roundRobinPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown replica policy: %s ", name)
+	}
+}
+
+type resolver struct {
+	primary  *node
+	replicas []*node
+	policy   policy
+}
+
+func newResolver(primary *node, replicas []*node, p policy) *resolver {
+	return &resolver{
+		primary:  primary,
+		replicas: replicas,
+		policy:   p,
+	}
+}
+
+func (r *resolver) Name() string {
+	return "db:resolver"
+}
+
+func (r *resolver) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		callback gormRegister
+		hook     gormHookFunc
+		name     string
+	}{
+		{cb.Query().Before("gorm:query"), r.read, "query"},
+		{cb.Row().Before("gorm:row"), r.read, "row"},
+		// 写操作需在默认事务开启前切回主库，否则事务会开在副本连接上
+		{cb.Create().Before("gorm:begin_transaction"), r.write, "create"},
+		{cb.Update().Before("gorm:begin_transaction"), r.write, "update"},
+		{cb.Delete().Before("gorm:begin_transaction"), r.write, "delete"},
+		{cb.Raw().Before("gorm:raw"), r.write, "raw"},
+	}
+
+	for _, h := range hooks {
+		if err := h.callback.Register("db:resolver:"+h.name, h.hook); err != nil {
+			return fmt.Errorf("callback register %s failed: %w", h.name, err)
+		}
+	}
+
+	if len(r.replicas) > 0 {
+		db.Dialector = primaryMigratorDialector{Dialector: db.Dialector}
+	}
+	return nil
+}
+
+func (r *resolver) read(tx *gorm.DB) {
+	// 复用的 Session 可能残留上一次读选中的副本，先还原到主库
+	r.resetPrimary(tx)
+
+	// 事务、db.Connection 等已绑定主库连接的语句不做切换
+	if tx.Statement.ConnPool != r.primary.pool || len(r.replicas) == 0 || !r.readable(tx) {
+		tx.InstanceSet(_NodeKey, r.primary.name)
+		return
+	}
+
+	replica := r.policy.resolve(r.replicas)
+	tx.Statement.ConnPool = replica.pool
+	tx.InstanceSet(_NodeKey, replica.name)
+}
+
+func (r *resolver) readable(tx *gorm.DB) bool {
+	stmt := tx.Statement
+	if isUsePrimary(stmt.Context) {
+		return false
+	}
+	if _, ok := stmt.Clauses[clause.Locking{}.Name()]; ok {
+		return false
+	}
+	if stmt.SQL.Len() > 0 {
+		sql := strings.TrimSpace(stmt.SQL.String())
+		if len(sql) < 6 || !strings.EqualFold(sql[:6], "select") {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *resolver) write(tx *gorm.DB) {
+	r.resetPrimary(tx)
+	tx.InstanceSet(_NodeKey, r.primary.name)
+}
+
+// resetPrimary 语句的连接指向副本时还原为主库
+func (r *resolver) resetPrimary(tx *gorm.DB) {
+	for _, replica := range r.replicas {
+		if tx.Statement.ConnPool == replica.pool {
+			tx.Statement.ConnPool = r.primary.pool
+			return
+		}
+	}
+}
+
+var _ gorm.SavePointerDialectorInterface = primaryMigratorDialector{}
+
+// primaryMigratorDialector Migrator 使用 UsePrimary 标记的 ctx
+type primaryMigratorDialector struct {
+	gorm.Dialector
+}
+
+func (d primaryMigratorDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return d.Dialector.Migrator(db.WithContext(UsePrimary(ctx)))
+}
+
+// SavePoint 嵌套事务依赖 Dialector 实现 SavePointerDialectorInterface，需要转发
+func (d primaryMigratorDialector) SavePoint(tx *gorm.DB, name string) error {
+	if sp, ok := d.Dialector.(gorm.SavePointerDialectorInterface); ok {
+		return sp.SavePoint(tx, name)
+	}
+	return gorm.ErrUnsupportedDriver
+}
+
+func (d primaryMigratorDialector) RollbackTo(tx *gorm.DB, name string) error {
+	if sp, ok := d.Dialector.(gorm.SavePointerDialectorInterface); ok {
+		return sp.RollbackTo(tx, name)
+	}
+	return gorm.ErrUnsupportedDriver
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/mysqlerr_helper"
+)
+
+// 事务通过 context 传递：
+// Transaction 开启事务后把 *gorm.DB 放入 ctx，下层通过 Repo.DB(ctx) 获取，
+// 已经在事务中时自动复用该事务，嵌套的 Transaction 使用 SAVEPOINT。
+//
+//	err := repo.Transaction(ctx, func(ctx context.Context) error {
+//		if err := repo.DB(ctx).Create(order).Error; err != nil {
+//			return err
+//		}
+//		return stockSvc.Deduct(ctx, order.ProductId) // 内部使用 repo.DB(ctx)，处于同一事务
+//	}, db.WithDeadlockRetry(3, 10*time.Millisecond))
+
+type txKey struct{}
+
+type TxOption func(*txOption)
+
+type txOption struct {
+	isolation sql.IsolationLevel
+	readOnly  bool
+
+	retries int
+	backoff time.Duration
+}
+
+// WithIsolation 设置事务隔离级别，嵌套事务中无效
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(opt *txOption) {
+		opt.isolation = level
+	}
+}
+
+// WithReadOnly 只读事务，嵌套事务中无效
+func WithReadOnly() TxOption {
+	return func(opt *txOption) {
+		opt.readOnly = true
+	}
+}
+
+// WithDeadlockRetry 遇到死锁(MySQL 1213)时重试整个事务，每次重试等待 backoff * 重试次数
+// 只对最外层事务生效，因为死锁发生时 MySQL 已回滚整个事务，SAVEPOINT 无法单独重试。
+func WithDeadlockRetry(retries int, backoff time.Duration) TxOption {
+	return func(opt *txOption) {
+		opt.retries = retries
+		opt.backoff = backoff
+	}
+}
+
+// txFromContext 获取 ctx 中的事务
+func txFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return tx, ok && tx != nil
+}
+
+// InTransaction 判断 ctx 是否处于事务中
+func InTransaction(ctx context.Context) bool {
+	_, ok := txFromContext(ctx)
+	return ok
+}
+
+func dbWithContext(base *gorm.DB, ctx context.Context) *gorm.DB {
+	if tx, ok := txFromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return base.WithContext(ctx)
+}
+
+func transaction(base *gorm.DB, ctx context.Context, fn func(ctx context.Context) error, options ...TxOption) error {
+	opt := new(txOption)
+	for _, f := range options {
+		f(opt)
+	}
+
+	run := func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	}
+
+	// 嵌套事务 SAVEPOINT
+	if tx, ok := txFromContext(ctx); ok {
+		return tx.WithContext(ctx).Transaction(run)
+	}
+
+	var sqlOpts []*sql.TxOptions
+	if opt.isolation != sql.LevelDefault || opt.readOnly {
+		sqlOpts = append(sqlOpts, &sql.TxOptions{
+			Isolation: opt.isolation,
+			ReadOnly:  opt.readOnly,
+		})
+	}
+
+	for i := 0; ; i++ {
+		err := base.WithContext(ctx).Transaction(run, sqlOpts...)
+		if err == nil || i >= opt.retries || !mysqlerr_helper.IsMysqlDeadlockError(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(opt.backoff * time.Duration(i+1)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/mysqlerr_helper"
+)
+
+// 乐观锁
+// 约定：模型包含整型字段 Version（列名 version），每次 UpdateWithVersion 成功后加 1。
+//
+//	type Order struct {
+//		Id      int
+//		Status  int
+//		Version int64
+//	}
+//
+//	err := repo.UpdateWithVersion(ctx, order, map[string]interface{}{"status": 2}, db.WithRetry(3, 10*time.Millisecond))
+//	if errors.Is(err, db.ErrConcurrentModification) { ... }
+//
+// 库存扣减使用 DecrementIfPositive，由单条 UPDATE ... WHERE stock >= n 保证不超卖：
+//
+//	err := repo.DecrementIfPositive(ctx, "product", "stock", map[string]interface{}{"id": 1}, 1)
+//	if errors.Is(err, db.ErrInsufficient) { ... }
+
+const _VersionField = "Version"
+
+var (
+	// ErrConcurrentModification 版本号已被其它请求修改
+	ErrConcurrentModification = errors.New("concurrent modification, version has changed. ")
+	// ErrInsufficient 字段值不足以扣减，或记录不存在
+	ErrInsufficient = errors.New("insufficient value or record not found. ")
+)
+
+type RetryOption func(*retryOption)
+
+type retryOption struct {
+	retries int
+	backoff time.Duration
+}
+
+// WithRetry 失败后最多重试 retries 次，每次重试等待 backoff * 重试次数
+// UpdateWithVersion 遇到版本冲突时会从主库重新加载 model 后重试；
+// 两者遇到死锁(MySQL 1213)时都会重试，但在事务中时不重试（整个事务已被回滚）。
+// 事务中的版本冲突同样不重试：REPEATABLE READ 下重新加载读到的仍是事务快照中的旧版本，应重试整个事务。
+func WithRetry(retries int, backoff time.Duration) RetryOption {
+	return func(opt *retryOption) {
+		opt.retries = retries
+		opt.backoff = backoff
+	}
+}
+
+func retry(ctx context.Context, options []RetryOption, fn func() error, onRetry func(err error) error) error {
+	opt := new(retryOption)
+	for _, f := range options {
+		f(opt)
+	}
+
+	for i := 0; ; i++ {
+		err := fn()
+		if err == nil || i >= opt.retries {
+			return err
+		}
+		if err = onRetry(err); err != nil {
+			return err
+		}
+
+		select {
+		case <-time.After(opt.backoff * time.Duration(i+1)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// retryable 返回 nil 表示可以重试
+func retryable(ctx context.Context, err error) error {
+	if mysqlerr_helper.IsMysqlDeadlockError(err) && !InTransaction(ctx) {
+		return nil
+	}
+	return err
+}
+
+// updateWithVersion 更新 model 并把 version + 1，version 不匹配时返回 ErrConcurrentModification
+// updates 中的值在重试时会重复使用，依赖旧值的更新请使用 gorm.Expr。
+func updateWithVersion(base *gorm.DB, ctx context.Context, model interface{}, updates map[string]interface{}, options ...RetryOption) error {
+	return retry(ctx, options,
+		func() error {
+			return updateVersionOnce(base, ctx, model, updates)
+		},
+		func(err error) error {
+			if !errors.Is(err, ErrConcurrentModification) {
+				return retryable(ctx, err)
+			}
+			if InTransaction(ctx) {
+				return err
+			}
+			// 重新加载最新版本，从主库读取避免副本延迟
+			return dbWithContext(base, UsePrimary(ctx)).First(model).Error
+		},
+	)
+}
+
+func updateVersionOnce(base *gorm.DB, ctx context.Context, model interface{}, updates map[string]interface{}) error {
+	db := dbWithContext(base, ctx)
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return fmt.Errorf("parse model failed: %w", err)
+	}
+
+	if reflect.ValueOf(model).Kind() != reflect.Ptr {
+		return fmt.Errorf("model %s must be a pointer ", stmt.Schema.Name)
+	}
+	rv := reflect.Indirect(reflect.ValueOf(model))
+	field := stmt.Schema.LookUpField(_VersionField)
+	if field == nil {
+		return fmt.Errorf("model %s has no %s field ", stmt.Schema.Name, _VersionField)
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return fmt.Errorf("model %s has no primary key ", stmt.Schema.Name)
+	}
+	// 主键为空时 Updates 会更新全表
+	if _, zero := pk.ValueOf(ctx, rv); zero {
+		return fmt.Errorf("model %s primary key is empty ", stmt.Schema.Name)
+	}
+
+	version, err := versionOf(field.ReflectValueOf(ctx, rv))
+	if err != nil {
+		return fmt.Errorf("model %s: %w", stmt.Schema.Name, err)
+	}
+
+	values := make(map[string]interface{}, len(updates)+1)
+	for k, v := range updates {
+		values[k] = v
+	}
+	values[field.DBName] = version + 1
+
+	// gorm 会把 updates 赋值到 model，更新失败时需要还原
+	snapshot := reflect.New(rv.Type()).Elem()
+	snapshot.Set(rv)
+
+	result := db.Model(model).
+		Where(clause.Eq{Column: clause.Column{Name: field.DBName}, Value: version}).
+		Updates(values)
+	if result.Error != nil {
+		rv.Set(snapshot)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		rv.Set(snapshot)
+		return fmt.Errorf("%w table: %s version: %d", ErrConcurrentModification, stmt.Schema.Table, version)
+	}
+	return field.Set(ctx, rv, version+1)
+}
+
+func versionOf(v reflect.Value) (int64, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), nil
+	default:
+		return 0, fmt.Errorf("%s field must be an integer, got %s ", _VersionField, v.Kind())
+	}
+}
+
+// decrementIfPositive UPDATE table SET column = column - n WHERE where AND column >= n
+// 没有行被更新时返回 ErrInsufficient
+func decrementIfPositive(base *gorm.DB, ctx context.Context, table, column string, where map[string]interface{}, n int64, options ...RetryOption) error {
+	if n <= 0 {
+		return fmt.Errorf("decrement n must be positive, got %d ", n)
+	}
+	// where 为空时会扣减全表
+	if len(where) == 0 {
+		return fmt.Errorf("decrement %s.%s without where condition ", table, column)
+	}
+
+	col := clause.Column{Name: column}
+	return retry(ctx, options,
+		func() error {
+			result := dbWithContext(base, ctx).Table(table).
+				Where(where).
+				Where(clause.Gte{Column: col, Value: n}).
+				UpdateColumn(column, gorm.Expr("? - ?", col, n))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("%w table: %s column: %s", ErrInsufficient, table, column)
+			}
+			return nil
+		},
+		func(err error) error {
+			return retryable(ctx, err)
+		},
+	)
+}
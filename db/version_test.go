@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type order struct {
+	ID      int
+	Status  int
+	Stock   int
+	Version int64
+}
+
+func newVersionRepo(t *testing.T) Repo {
+	repo, err := New(&DBConfig{
+		Driver:      DriverSQLite,
+		Name:        filepath.Join(t.TempDir(), "version.db"),
+		MaxOpenConn: 1,
+		ServerName:  "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = repo.DbClose()
+	})
+
+	assert.NoError(t, repo.GetDb().AutoMigrate(&order{}))
+	assert.NoError(t, repo.GetDb().Create(&order{ID: 1, Stock: 10}).Error)
+	return repo
+}
+
+func TestUpdateWithVersion(t *testing.T) {
+	ctx := context.Background()
+	repo := newVersionRepo(t)
+
+	var first, second order
+	assert.NoError(t, repo.DB(ctx).First(&first, 1).Error)
+	assert.NoError(t, repo.DB(ctx).First(&second, 1).Error)
+
+	assert.NoError(t, repo.UpdateWithVersion(ctx, &first, map[string]interface{}{"status": 1}))
+	assert.Equal(t, int64(1), first.Version)
+
+	// second 持有旧版本
+	err := repo.UpdateWithVersion(ctx, &second, map[string]interface{}{"status": 2})
+	assert.ErrorIs(t, err, ErrConcurrentModification)
+	assert.Equal(t, int64(0), second.Version)
+	assert.Equal(t, 0, second.Status)
+
+	// 重试时重新加载最新版本
+	err = repo.UpdateWithVersion(ctx, &second, map[string]interface{}{"stock": gorm.Expr("stock - 1")}, WithRetry(1, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), second.Version)
+
+	var got order
+	assert.NoError(t, repo.DB(ctx).First(&got, 1).Error)
+	assert.Equal(t, 1, got.Status)
+	assert.Equal(t, 9, got.Stock)
+	assert.Equal(t, int64(2), got.Version)
+
+	// 事务中版本冲突不重试
+	var stale order
+	assert.NoError(t, repo.DB(ctx).First(&stale, 1).Error)
+	stale.Version = 0
+	err = repo.Transaction(ctx, func(ctx context.Context) error {
+		return repo.UpdateWithVersion(ctx, &stale, map[string]interface{}{"status": 3}, WithRetry(3, 0))
+	})
+	assert.ErrorIs(t, err, ErrConcurrentModification)
+	assert.Equal(t, int64(0), stale.Version)
+
+	// 主键为空时拒绝更新
+	assert.Error(t, repo.UpdateWithVersion(ctx, &order{}, map[string]interface{}{"status": 3}))
+	assert.Error(t, repo.UpdateWithVersion(ctx, &product{ID: 1}, map[string]interface{}{"name": "x"}))
+}
+
+func TestDecrementIfPositive(t *testing.T) {
+	ctx := context.Background()
+	repo := newVersionRepo(t)
+	where := map[string]interface{}{"id": 1}
+
+	var (
+		wg      sync.WaitGroup
+		success int32
+	)
+	for i := 0; i < 15; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.DecrementIfPositive(ctx, "order", "stock", where, 1)
+			switch {
+			case err == nil:
+				atomic.AddInt32(&success, 1)
+			case !errors.Is(err, ErrInsufficient):
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(10), success)
+
+	var got order
+	assert.NoError(t, repo.DB(ctx).First(&got, 1).Error)
+	assert.Equal(t, 0, got.Stock)
+
+	assert.ErrorIs(t, repo.DecrementIfPositive(ctx, "order", "stock", map[string]interface{}{"id": 2}, 1), ErrInsufficient)
+	assert.Error(t, repo.DecrementIfPositive(ctx, "order", "stock", nil, 1))
+	assert.Error(t, repo.DecrementIfPositive(ctx, "order", "stock", where, 0))
+}
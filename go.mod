@@ -4,6 +4,7 @@ go 1.16
 
 require (
 	github.com/VividCortex/mysqlerr v1.0.0
+	github.com/alicebob/miniredis/v2 v2.23.0
 	github.com/bsm/redislock v0.7.2
 	github.com/gin-contrib/pprof v1.3.0
 	github.com/gin-gonic/gin v1.7.7
@@ -12,6 +13,7 @@ require (
 	github.com/go-sql-driver/mysql v1.6.0
 	github.com/gogf/gf/v2 v2.1.2
 	github.com/golang-jwt/jwt v3.2.2+incompatible
+	github.com/gorilla/websocket v1.5.0
 	github.com/grpc-ecosystem/go-grpc-prometheus v1.2.0
 	github.com/juju/ratelimit v1.0.2 // indirect
 	github.com/prometheus/client_golang v1.12.2
@@ -20,6 +22,7 @@ require (
 	github.com/stretchr/testify v1.7.1
 	github.com/swaggo/gin-swagger v1.4.1
 	github.com/tidwall/gjson v1.12.1
+	github.com/ugorji/go/codec v1.2.6
 	go.etcd.io/etcd/client/v3 v3.5.4
 	go.opentelemetry.io/otel v1.7.0
 	go.opentelemetry.io/otel/exporters/jaeger v1.3.0
@@ -32,8 +35,11 @@ require (
 	golang.org/x/crypto v0.0.0-20211215153901-e495a2d5b3d3
 	golang.org/x/time v0.0.0-20220224211638-0e9765cccd65
 	google.golang.org/grpc v1.45.0
+	google.golang.org/protobuf v1.27.1
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 	gorm.io/driver/mysql v1.3.3
+	gorm.io/driver/postgres v1.3.5
+	gorm.io/driver/sqlite v1.3.2
 	gorm.io/gorm v1.23.4
 	gorm.io/plugin/prometheus v0.0.0-20220517015831-ca6bfaf20bf4
 )
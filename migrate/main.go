@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/HYY-yu/seckill.pkg/db"
+)
+
+// 数据库迁移的小工具
+// migrate -driver mysql -addr 127.0.0.1:3306 -user root -pass 123 -name seckill -dir ./migrations up
+// 命令：up | down [steps] | status
+func main() {
+	if err := run(); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run 返回错误而不是直接退出，保证 defer 的 DbClose 能执行
+func run() error {
+	var (
+		cfg   db.DBConfig
+		dir   string
+		table string
+	)
+	flag.StringVar(&cfg.Driver, "driver", db.DriverMySQL, "mysql | postgres | sqlite")
+	flag.StringVar(&cfg.Addr, "addr", "", "database addr")
+	flag.StringVar(&cfg.User, "user", "", "database user")
+	flag.StringVar(&cfg.Pass, "pass", os.Getenv("DB_PASS"), "database password, default $DB_PASS")
+	flag.StringVar(&cfg.Name, "name", "", "database name, sqlite file path")
+	flag.StringVar(&dir, "dir", "migrations", "migration sql files dir")
+	flag.StringVar(&table, "table", "schema_migrations", "migration table")
+	flag.Parse()
+
+	cfg.ServerName = "migrate"
+	repo, err := db.New(&cfg)
+	if err != nil {
+		return err
+	}
+	defer repo.DbClose()
+
+	m := db.NewMigrator(repo.GetDb(), db.WithMigrationTable(table))
+	if err = m.LoadFS(os.DirFS(dir), "."); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch flag.Arg(0) {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		steps := 1
+		if flag.Arg(1) != "" {
+			if _, err = fmt.Sscanf(flag.Arg(1), "%d", &steps); err != nil {
+				return fmt.Errorf("invalid steps %s ", flag.Arg(1))
+			}
+		}
+		return m.Down(ctx, steps)
+	case "status":
+		return printStatus(ctx, m)
+	default:
+		return fmt.Errorf("unknown command %q, use up | down [steps] | status ", flag.Arg(0))
+	}
+}
+
+func printStatus(ctx context.Context, m *db.Migrator) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tSTATE")
+	for _, s := range status {
+		appliedAt := "-"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+
+		var state []string
+		switch {
+		case !s.Applied:
+			state = append(state, "pending")
+		case s.Missing:
+			state = append(state, "missing")
+		default:
+			state = append(state, "applied")
+		}
+		if s.Drift {
+			state = append(state, "drift")
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, appliedAt, strings.Join(state, ","))
+	}
+	return w.Flush()
+}
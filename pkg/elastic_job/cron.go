@@ -20,6 +20,8 @@ type ElasticJob interface {
 	// 利用ETCD做分布式锁，保证回调链只有一个能被执行。
 	RegisterHandler(handlerTag string, h Handler)
 
+	// Ping 检查存储器的连接，可用于 core.WithHealthCheck
+	Ping(ctx context.Context) error
 	Close() error
 }
 
@@ -233,6 +235,10 @@ func (e *elasticJob) RegisterHandler(handlerTag string, h Handler) {
 	e.handlers.Store(handlerTag, h)
 }
 
+func (e *elasticJob) Ping(ctx context.Context) error {
+	return e.store.Ping(ctx)
+}
+
 func (e *elasticJob) Close() error {
 	e.cancel()
 	_ = e.logger.Sync()
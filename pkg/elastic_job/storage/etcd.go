@@ -177,6 +177,20 @@ func (e *etcdStorage) UnLock(key string) error {
 	return nil
 }
 
+// Ping 任一节点状态正常即可
+func (e *etcdStorage) Ping(ctx context.Context) error {
+	var err error
+	for _, endpoint := range e.etcdClient.Endpoints() {
+		if _, err = e.etcdClient.Status(ctx, endpoint); err == nil {
+			return nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("etcd no endpoints")
+	}
+	return err
+}
+
 func (e *etcdStorage) Close() error {
 	// reversion 持久化
 	_, _ = e.etcdClient.Put(e.ctx, KeyForWatchReversion, strconv.Itoa(int(e.reversion)))
@@ -114,6 +114,10 @@ func (r redisStorage) UnLock(key string) error {
 	return nil
 }
 
+func (r redisStorage) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
 func (r redisStorage) Close() error {
 	var errs []error
 
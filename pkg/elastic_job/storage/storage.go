@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"time"
 )
@@ -31,6 +32,8 @@ type BackendStorage interface {
 	// UnLock   分布式锁
 	UnLock(key string) error
 
+	// Ping 检查存储器的连接
+	Ping(ctx context.Context) error
 	Close() error
 }
 
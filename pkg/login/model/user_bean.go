@@ -12,6 +12,8 @@ type LoginResponse struct {
 	Token interface{} `json:"token"` // LoginResponseByRefreshToken or LoginResponseByBlackList
 }
 
+// 旧版本的 key 前缀，新 key 见 login.KeyPrefix
+// 仅用于兼容读取升级前写入的凭证，待旧 key 全部过期后删除。
 const (
 	RedisRefreshTokenKeyPrefix = "sk:refresh:"
 	RedisBlackListKeyPrefix    = "sk:black_list:"
@@ -36,8 +36,20 @@ type LoginTokenSystem interface {
 	RefreshToken(ctx context.Context, oldToken string) (*model.LoginResponse, error)
 }
 
+// KeyPrefix 凭证 key 的命名空间
+// 登录服务写入、其他服务校验，需要所有服务读写同一组 key，因此不使用各服务 cache.Repo 以 serverName 为前缀的 KeySchema。
+const KeyPrefix = "sk:login"
+
+var keySchema = cache.NewKeySchema(KeyPrefix)
+
+// NewByRefreshToken RefreshToken 的 key 为 sk:login:refresh_token:v1:<refreshToken>
+// 旧版本的 key（model.RedisRefreshTokenKeyPrefix）仍可读取、失效，待其自然过期。
 func NewByRefreshToken(cfg *RefreshTokenConfig, cache cache.Repo) LoginTokenSystem {
-	return &RefreshTokenSystem{cfg: cfg, cache: cache}
+	return &RefreshTokenSystem{
+		cfg:      cfg,
+		cache:    cache,
+		tokenKey: keySchema.Template("refresh_token", "token"),
+	}
 }
 
 type RefreshTokenConfig struct {
@@ -49,7 +61,8 @@ type RefreshTokenConfig struct {
 type RefreshTokenSystem struct {
 	cfg *RefreshTokenConfig
 
-	cache cache.Repo
+	cache    cache.Repo
+	tokenKey *cache.KeyTemplate
 }
 
 func (r *RefreshTokenSystem) GenerateToken(ctx context.Context, userId int, userName string) (*model.LoginResponse, error) {
@@ -70,7 +83,7 @@ func (r *RefreshTokenSystem) GenerateToken(ctx context.Context, userId int, user
 
 	userJson, _ := json.Marshal(userClaims)
 
-	err = r.cache.Set(ctx, model.RedisRefreshTokenKeyPrefix+refreshToken, string(userJson), r.cfg.RefreshDuration)
+	err = r.cache.Set(ctx, r.tokenKey.Build(refreshToken), string(userJson), r.cfg.RefreshDuration)
 	if err != nil {
 		return nil, err
 	}
@@ -89,12 +102,16 @@ func (r *RefreshTokenSystem) TokenCancelById(ctx context.Context, userId int, us
 }
 
 func (r *RefreshTokenSystem) TokenCancel(ctx context.Context, refreshToken string) error {
+	_ = r.cache.Del(ctx, r.tokenKey.Build(refreshToken))
 	_ = r.cache.Del(ctx, model.RedisRefreshTokenKeyPrefix+refreshToken)
 	return nil
 }
 
 func (r *RefreshTokenSystem) RefreshToken(ctx context.Context, refreshToken string) (*model.LoginResponse, error) {
-	userJson, err := r.cache.Get(ctx, model.RedisRefreshTokenKeyPrefix+refreshToken)
+	userJson, err := r.cache.Get(ctx, r.tokenKey.Build(refreshToken))
+	if errors.Is(err, redis.Nil) {
+		userJson, err = r.cache.Get(ctx, model.RedisRefreshTokenKeyPrefix+refreshToken)
+	}
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return nil, model.RefreshTokenExpired
@@ -121,8 +138,14 @@ func (r *RefreshTokenSystem) generateRefreshToken(secret string, userId int, use
 	return fmt.Sprintf("%x", hencrypt.Sum(nil))
 }
 
+// NewByBlackList 黑名单的 key 为 sk:login:black_list:v1:<userId>:<userName>
+// 旧版本的 key（model.RedisBlackListKeyPrefix）在校验时仍会读取，待其自然过期。
 func NewByBlackList(cfg *BlackListConfig, cache cache.Repo) LoginTokenSystem {
-	return &BlackListSystem{cfg: cfg, cache: cache}
+	return &BlackListSystem{
+		cfg:          cfg,
+		cache:        cache,
+		blackListKey: keySchema.Template("black_list", "user_id", "user_name"),
+	}
 }
 
 type BlackListConfig struct {
@@ -133,7 +156,8 @@ type BlackListConfig struct {
 type BlackListSystem struct {
 	cfg *BlackListConfig
 
-	cache cache.Repo
+	cache        cache.Repo
+	blackListKey *cache.KeyTemplate
 }
 
 func (r *BlackListSystem) GenerateToken(ctx context.Context, userId int, userName string) (*model.LoginResponse, error) {
@@ -149,19 +173,18 @@ func (r *BlackListSystem) GenerateToken(ctx context.Context, userId int, userNam
 	}, nil
 }
 
-func (r *BlackListSystem) blackListKey(userId int64, userName string) string {
-	return fmt.Sprintf("%s_%d_%s", model.RedisBlackListKeyPrefix, userId, userName)
-}
-
 // CheckBlackList 验证时，需要验证是否在黑名单
 func (r *BlackListSystem) CheckBlackList(ctx context.Context, accessToken string) (bool, error) {
 	claim, err := token.New(r.cfg.Secret).JwtParseUnsafe(accessToken)
 	if err != nil {
 		return false, fmt.Errorf("this token is unvalid ")
 	}
-	key := r.blackListKey(claim.UserID, claim.UserName)
+	key := r.blackListKey.Build(claim.UserID, claim.UserName)
 
 	a, err := r.cache.Get(ctx, key)
+	if errors.Is(err, redis.Nil) {
+		a, err = r.cache.Get(ctx, legacyBlackListKey(claim.UserID, claim.UserName))
+	}
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return false, nil
@@ -171,9 +194,14 @@ func (r *BlackListSystem) CheckBlackList(ctx context.Context, accessToken string
 	return a == "1", nil
 }
 
+// legacyBlackListKey 旧版本的黑名单 key
+func legacyBlackListKey(userId int64, userName string) string {
+	return fmt.Sprintf("%s_%d_%s", model.RedisBlackListKeyPrefix, userId, userName)
+}
+
 // TokenCancelById 根据 userId 失效 Token
 func (r *BlackListSystem) TokenCancelById(ctx context.Context, userId int, userName string) error {
-	key := r.blackListKey(int64(userId), userName)
+	key := r.blackListKey.Build(userId, userName)
 
 	err := r.cache.Set(ctx, key, "1", r.cfg.ExpireDuration)
 	if err != nil {
@@ -191,7 +219,7 @@ func (r *BlackListSystem) TokenCancel(ctx context.Context, accessToken string) e
 
 	// 获取 claim 中的 userId+userName
 	// 进入黑名单的是这个人，所以他相关的所有AccessToken都会失效
-	key := r.blackListKey(claim.UserID, claim.UserName)
+	key := r.blackListKey.Build(claim.UserID, claim.UserName)
 	err = r.cache.Set(ctx, key, "1", r.cfg.ExpireDuration)
 	if err != nil {
 		return err
@@ -13,11 +13,7 @@ import (
 )
 
 func TestRefreshToken(t *testing.T) {
-	cacheRepo, err := cache.New("test", &cache.RedisConf{
-		Addr: "127.0.0.1:6379",
-		Pass: "redis",
-	})
-	assert.NoError(t, err)
+	cacheRepo := cache.NewMemoryRepo()
 
 	cfg := &RefreshTokenConfig{
 		Secret:          "test_secret",
@@ -72,6 +68,7 @@ func TestRefreshToken(t *testing.T) {
 		assert.NoError(t, err)
 
 		refreshResp := resp.Token.(*model.LoginResponseByRefreshToken)
+		assert.True(t, cacheRepo.Exists(ctx, "sk:login:refresh_token:v1:"+refreshResp.RefreshToken))
 
 		err = system.TokenCancel(ctx, refreshResp.RefreshToken)
 		assert.NoError(t, err)
@@ -83,11 +80,7 @@ func TestRefreshToken(t *testing.T) {
 }
 
 func TestBlackList(t *testing.T) {
-	cacheRepo, err := cache.New("test", &cache.RedisConf{
-		Addr: "127.0.0.1:6379",
-		Pass: "redis",
-	})
-	assert.NoError(t, err)
+	cacheRepo := cache.NewMemoryRepo()
 
 	cfg := &BlackListConfig{
 		Secret:         "test_secret",
@@ -126,13 +119,16 @@ func TestBlackList(t *testing.T) {
 		newResp, err := system.RefreshToken(ctx, refreshResp.AccessToken)
 		assert.NoError(t, err)
 
-		time.Sleep(time.Second * 4)
+		// 新 token 的有效期为 2s
+		time.Sleep(time.Second)
 
 		newRefreshResp := newResp.Token.(*model.LoginResponseByBlackList)
 
 		// 依然有效
 		claims, err := token.New(cfg.Secret).JwtParse(newRefreshResp.AccessToken)
-		assert.NoError(t, err)
+		if !assert.NoError(t, err) {
+			return
+		}
 		assert.Equal(t, claims.UserID, int64(userId))
 		assert.Equal(t, claims.UserName, userName)
 
@@ -153,9 +149,46 @@ func TestBlackList(t *testing.T) {
 		// 进入黑名单
 		err = system.TokenCancel(ctx, refreshResp.AccessToken)
 		assert.NoError(t, err)
+		assert.True(t, cacheRepo.Exists(ctx, "sk:login:black_list:v1:1:UserName"))
 
 		result, err = system.(*BlackListSystem).CheckBlackList(ctx, refreshResp.AccessToken)
 		assert.NoError(t, err)
 		assert.Equal(t, true, result)
 	})
 }
+
+func TestLegacyKeys(t *testing.T) {
+	cacheRepo := cache.NewMemoryRepo()
+	ctx := context.Background()
+
+	t.Run("refresh token", func(t *testing.T) {
+		system := NewByRefreshToken(&RefreshTokenConfig{
+			Secret:          "test_secret",
+			ExpireDuration:  time.Second * 2,
+			RefreshDuration: time.Second * 10,
+		}, cacheRepo)
+
+		// 升级前写入的 refresh token 仍可刷新
+		assert.NoError(t, cacheRepo.Set(ctx, model.RedisRefreshTokenKeyPrefix+"legacy", `{"user_id":1,"user_name":"UserName"}`, time.Minute))
+		resp, err := system.RefreshToken(ctx, "legacy")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.Token.(*model.LoginResponseByRefreshToken).RefreshToken)
+		assert.False(t, cacheRepo.Exists(ctx, model.RedisRefreshTokenKeyPrefix+"legacy"))
+	})
+
+	t.Run("black list", func(t *testing.T) {
+		system := NewByBlackList(&BlackListConfig{
+			Secret:         "test_secret",
+			ExpireDuration: time.Second * 10,
+		}, cacheRepo)
+		resp, err := system.GenerateToken(ctx, 2, "Legacy")
+		assert.NoError(t, err)
+		accessToken := resp.Token.(*model.LoginResponseByBlackList).AccessToken
+
+		// 升级前加入黑名单的用户仍被拒绝
+		assert.NoError(t, cacheRepo.Set(ctx, "sk:black_list:_2_Legacy", "1", time.Minute))
+		result, err := system.(*BlackListSystem).CheckBlackList(ctx, accessToken)
+		assert.NoError(t, err)
+		assert.True(t, result)
+	})
+}
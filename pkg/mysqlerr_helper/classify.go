@@ -0,0 +1,114 @@
+package mysqlerr_helper
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+
+	"github.com/VividCortex/mysqlerr"
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+// MySQL 错误分类
+// 把驱动返回的错误归类为下列哨兵错误，上层通过 errors.Is 判断，
+// Handler 中可以直接 ctx.AbortWithError(mysqlerr_helper.ToResponseError(err))。
+
+var (
+	// ErrDuplicateKey 唯一索引冲突 1062
+	ErrDuplicateKey = errors.New("mysql: duplicate key ")
+	// ErrForeignKey 外键约束 1451 1452 1216 1217
+	ErrForeignKey = errors.New("mysql: foreign key constraint fails ")
+	// ErrDeadlock 死锁 1213，事务已被回滚
+	ErrDeadlock = errors.New("mysql: deadlock found ")
+	// ErrLockWaitTimeout 锁等待超时 1205，只回滚当前语句
+	ErrLockWaitTimeout = errors.New("mysql: lock wait timeout exceeded ")
+	// ErrDataTooLong 数据超出字段长度 1406
+	ErrDataTooLong = errors.New("mysql: data too long ")
+	// ErrConnectionLost 连接断开
+	ErrConnectionLost = errors.New("mysql: connection lost ")
+	// ErrReadOnly 写入只读实例 1290 1792 1836
+	ErrReadOnly = errors.New("mysql: server is read only ")
+)
+
+var mysqlErrNumbers = map[uint16]error{
+	mysqlerr.ER_DUP_ENTRY:                             ErrDuplicateKey,
+	mysqlerr.ER_ROW_IS_REFERENCED_2:                   ErrForeignKey,
+	mysqlerr.ER_NO_REFERENCED_ROW_2:                   ErrForeignKey,
+	mysqlerr.ER_NO_REFERENCED_ROW:                     ErrForeignKey,
+	mysqlerr.ER_ROW_IS_REFERENCED:                     ErrForeignKey,
+	mysqlerr.ER_LOCK_DEADLOCK:                         ErrDeadlock,
+	mysqlerr.ER_LOCK_WAIT_TIMEOUT:                     ErrLockWaitTimeout,
+	mysqlerr.ER_DATA_TOO_LONG:                         ErrDataTooLong,
+	mysqlerr.ER_OPTION_PREVENTS_STATEMENT:             ErrReadOnly,
+	mysqlerr.ER_CANT_EXECUTE_IN_READ_ONLY_TRANSACTION: ErrReadOnly,
+	mysqlerr.ER_READ_ONLY_MODE:                        ErrReadOnly,
+}
+
+// 哨兵错误 -> HTTP Code + Business Code
+var responseCodes = map[error][2]int{
+	ErrDuplicateKey:    {http.StatusConflict, response.DBDuplicateKey},
+	ErrForeignKey:      {http.StatusConflict, response.DBForeignKey},
+	ErrDeadlock:        {http.StatusServiceUnavailable, response.DBDeadlock},
+	ErrLockWaitTimeout: {http.StatusServiceUnavailable, response.DBLockWaitTimeout},
+	ErrDataTooLong:     {http.StatusBadRequest, response.DBDataTooLong},
+	ErrConnectionLost:  {http.StatusServiceUnavailable, response.DBUnavailable},
+	ErrReadOnly:        {http.StatusServiceUnavailable, response.DBReadOnly},
+}
+
+// Classify 返回 err 对应的哨兵错误，无法归类时返回 nil
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var driverErr *mysql.MySQLError
+	if errors.As(err, &driverErr) {
+		return mysqlErrNumbers[driverErr.Number]
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return ErrConnectionLost
+	}
+	return nil
+}
+
+// Translate 把驱动错误包装为哨兵错误，errors.Is 可匹配哨兵错误，errors.As 仍可获取 *mysql.MySQLError
+// 无法归类时原样返回
+func Translate(err error) error {
+	kind := Classify(err)
+	if kind == nil {
+		return err
+	}
+	return &classifiedError{kind: kind, err: err}
+}
+
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.kind.Error() + e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return target == e.kind
+}
+
+// ToResponseError 转换为 response.Error，无法归类的错误返回 500 ServerError
+func ToResponseError(err error) response.Error {
+	if err == nil {
+		return nil
+	}
+
+	codes, ok := responseCodes[Classify(err)]
+	if !ok {
+		return response.NewErrorAutoMsg(http.StatusInternalServerError, response.ServerError).WithErr(err)
+	}
+	return response.NewErrorAutoMsg(codes[0], codes[1]).WithErr(err)
+}
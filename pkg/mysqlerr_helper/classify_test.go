@@ -0,0 +1,70 @@
+package mysqlerr_helper
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/VividCortex/mysqlerr"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/HYY-yu/seckill.pkg/pkg/response"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"nil", nil, nil},
+		{"dup", &mysql.MySQLError{Number: mysqlerr.ER_DUP_ENTRY}, ErrDuplicateKey},
+		{"fk", &mysql.MySQLError{Number: mysqlerr.ER_NO_REFERENCED_ROW_2}, ErrForeignKey},
+		{"deadlock wrapped", fmt.Errorf("create order: %w", &mysql.MySQLError{Number: mysqlerr.ER_LOCK_DEADLOCK}), ErrDeadlock},
+		{"lock wait", &mysql.MySQLError{Number: mysqlerr.ER_LOCK_WAIT_TIMEOUT}, ErrLockWaitTimeout},
+		{"too long", &mysql.MySQLError{Number: mysqlerr.ER_DATA_TOO_LONG}, ErrDataTooLong},
+		{"read only", &mysql.MySQLError{Number: mysqlerr.ER_OPTION_PREVENTS_STATEMENT}, ErrReadOnly},
+		{"bad conn", driver.ErrBadConn, ErrConnectionLost},
+		{"invalid conn", mysql.ErrInvalidConn, ErrConnectionLost},
+		{"unknown number", &mysql.MySQLError{Number: mysqlerr.ER_BAD_FIELD_ERROR}, nil},
+		{"other", errors.New("other"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Classify(tt.err))
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	raw := &mysql.MySQLError{Number: mysqlerr.ER_DUP_ENTRY, Message: "Duplicate entry '1' for key 'PRIMARY'"}
+	err := Translate(raw)
+	assert.ErrorIs(t, err, ErrDuplicateKey)
+
+	var driverErr *mysql.MySQLError
+	assert.True(t, errors.As(err, &driverErr))
+	assert.True(t, IsMysqlDupEntryError(driverErr))
+
+	other := errors.New("other")
+	assert.Equal(t, other, Translate(other))
+}
+
+func TestToResponseError(t *testing.T) {
+	assert.Nil(t, ToResponseError(nil))
+
+	e := ToResponseError(&mysql.MySQLError{Number: mysqlerr.ER_DUP_ENTRY})
+	assert.Equal(t, http.StatusConflict, e.GetHttpCode())
+	assert.Equal(t, response.DBDuplicateKey, e.GetBusinessCode())
+	assert.Equal(t, response.Text(response.DBDuplicateKey), e.GetMsg())
+
+	e = ToResponseError(driver.ErrBadConn)
+	assert.Equal(t, http.StatusServiceUnavailable, e.GetHttpCode())
+	assert.Equal(t, response.DBUnavailable, e.GetBusinessCode())
+
+	e = ToResponseError(errors.New("other"))
+	assert.Equal(t, http.StatusInternalServerError, e.GetHttpCode())
+	assert.Equal(t, response.ServerError, e.GetBusinessCode())
+}
@@ -1,6 +1,8 @@
 package mysqlerr_helper
 
 import (
+	"errors"
+
 	"github.com/VividCortex/mysqlerr"
 	"github.com/go-sql-driver/mysql"
 	"github.com/gogf/gf/v2/errors/gerror"
@@ -19,3 +21,12 @@ func IsMysqlDupEntryError(err error) bool {
 	}
 	return false
 }
+
+// IsMysqlDeadlockError Mysql返回死锁，事务已被回滚，可以重试整个事务
+func IsMysqlDeadlockError(err error) bool {
+	var driverErr *mysql.MySQLError
+	if errors.As(err, &driverErr) {
+		return driverErr.Number == mysqlerr.ER_LOCK_DEADLOCK
+	}
+	return false
+}
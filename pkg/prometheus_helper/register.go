@@ -0,0 +1,19 @@
+package prometheus_helper
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterOrExisting 注册到默认 Registry，已注册过相同的指标时返回已注册的 Collector
+// 相同 serverName 多次创建 Repo、Engine 时复用指标，不会因重复注册而 panic
+func RegisterOrExisting(c prometheus.Collector) prometheus.Collector {
+	if err := prometheus.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			return are.ExistingCollector
+		}
+	}
+	return c
+}
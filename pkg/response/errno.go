@@ -14,6 +14,8 @@ type Error interface {
 	error
 	// WithErr 设置错误信息
 	WithErr(err error) Error
+	// WithData 设置返回的 Data，如参数校验失败的字段详情
+	WithData(data interface{}) Error
 	// GetBusinessCode 获取 Business Code
 	GetBusinessCode() int
 	// GetHttpCode 获取 HTTP Code
@@ -22,6 +24,8 @@ type Error interface {
 	GetMsg() string
 	// GetErr 获取错误信息
 	GetErr() error
+	// GetData 获取 Data
+	GetData() interface{}
 	// ToString 返回 JSON 格式的错误详情
 	ToString() string
 }
@@ -31,6 +35,7 @@ type err struct {
 	BusinessCode int    // Business Code
 	Message      string // 描述信息
 	Err          error  // 错误信息
+	Data         interface{}
 }
 
 // NewError 新建一个 Error
@@ -79,6 +84,12 @@ func (e *err) WithErr(err error) Error {
 	return e
 }
 
+// WithData 设置返回的 Data
+func (e *err) WithData(data interface{}) Error {
+	e.Data = data
+	return e
+}
+
 // GetHttpCode 获取 HttpCode
 func (e *err) GetHttpCode() int {
 	return e.HttpCode
@@ -99,6 +110,11 @@ func (e *err) GetErr() error {
 	return e.Err
 }
 
+// GetData 获取 Data
+func (e *err) GetData() interface{} {
+	return e.Data
+}
+
 // ToString 返回 JSON 格式的错误详情
 func (e *err) ToString() string {
 	err := &struct {
@@ -33,9 +33,51 @@ const (
 	ParamBindError     = 10004
 
 	TokenExpired = 10005
+
+	// 数据库错误，由 mysqlerr_helper.ToResponseError 转换
+	DBDuplicateKey    = 10006
+	DBForeignKey      = 10007
+	DBDeadlock        = 10008
+	DBLockWaitTimeout = 10009
+	DBDataTooLong     = 10010
+	DBUnavailable     = 10011
+	DBReadOnly        = 10012
+
+	// 幂等校验，由 middleware.Idempotency 返回
+	RequestInProgress      = 10013
+	IdempotencyKeyMismatch = 10014
+
+	// 签名校验，由 middleware.SignatureVerify 返回
+	SignatureError   = 10015
+	SignatureExpired = 10016
 )
 
 // Text 注册表转换
 func Text(code int) string {
 	return codeText[code]
 }
+
+// Codes 注册的全部业务码及说明
+func Codes() map[int]string {
+	codes := make(map[int]string, len(codeText))
+	for code, text := range codeText {
+		codes[code] = text
+	}
+	return codes
+}
+
+// FieldError 参数校验失败的字段详情，放在 ParamBindError 的 Data 中
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// RuleText 校验规则的错误信息模板，未注册的规则返回默认模板
+// 模板中的 {attribute} {value} {min} {max} 等由校验器替换
+func RuleText(rule string) string {
+	if text, ok := ruleText[rule]; ok {
+		return text
+	}
+	return ruleText[_DefaultRule]
+}
@@ -5,4 +5,58 @@ var codeText = map[int]string{
 	TooManyRequests:    "请求发送过多",
 	AuthorizationError: "鉴权失败",
 	ParamBindError:     "请检查参数是否在正确",
+
+	DBDuplicateKey:    "数据已存在",
+	DBForeignKey:      "关联数据不存在或仍被引用",
+	DBDeadlock:        "系统繁忙，请稍后重试",
+	DBLockWaitTimeout: "系统繁忙，请稍后重试",
+	DBDataTooLong:     "数据长度超出限制",
+	DBUnavailable:     "数据库暂时不可用，请稍后重试",
+	DBReadOnly:        "数据库暂时不可写，请稍后重试",
+
+	RequestInProgress:      "请求正在处理中，请勿重复提交",
+	IdempotencyKeyMismatch: "Idempotency-Key 已用于其他请求",
+
+	SignatureError:   "签名校验失败",
+	SignatureExpired: "签名已过期，请重新签名",
+}
+
+const _DefaultRule = "__default__"
+
+// ruleText 校验规则的错误信息，规则名同 gvalid
+var ruleText = map[string]string{
+	"required":             "{attribute}不能为空",
+	"required-if":          "{attribute}不能为空",
+	"required-unless":      "{attribute}不能为空",
+	"required-with":        "{attribute}不能为空",
+	"required-with-all":    "{attribute}不能为空",
+	"required-without":     "{attribute}不能为空",
+	"required-without-all": "{attribute}不能为空",
+	"date":                 "{attribute}不是有效的日期",
+	"datetime":             "{attribute}不是有效的日期时间",
+	"date-format":          "{attribute}日期格式应当为{pattern}",
+	"email":                "{attribute}不是有效的邮箱地址",
+	"phone":                "{attribute}不是有效的手机号",
+	"password":             "{attribute}密码格式不正确",
+	"password2":            "{attribute}密码格式不正确",
+	"password3":            "{attribute}密码格式不正确",
+	"resident-id":          "{attribute}不是有效的身份证号",
+	"ip":                   "{attribute}不是有效的IP地址",
+	"url":                  "{attribute}不是有效的URL",
+	"length":               "{attribute}长度应当为{min}到{max}个字符",
+	"min-length":           "{attribute}长度不能小于{min}",
+	"max-length":           "{attribute}长度不能大于{max}",
+	"size":                 "{attribute}长度应当为{size}",
+	"between":              "{attribute}应当在{min}到{max}之间",
+	"min":                  "{attribute}不能小于{min}",
+	"max":                  "{attribute}不能大于{max}",
+	"json":                 "{attribute}不是有效的JSON",
+	"integer":              "{attribute}应当为整数",
+	"boolean":              "{attribute}应当为布尔值",
+	"same":                 "{attribute}应当与{pattern}相同",
+	"different":            "{attribute}不能与{pattern}相同",
+	"in":                   "{attribute}应当为{pattern}中的一个",
+	"not-in":               "{attribute}不能为{pattern}中的一个",
+	"regex":                "{attribute}格式不正确",
+	_DefaultRule:           "{attribute}格式不正确",
 }
@@ -3,6 +3,7 @@ package shutdown
 import (
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 )
 
@@ -15,16 +16,22 @@ type Hook interface {
 
 	// Close register shutdown handles
 	Close(funcs ...func())
+
+	// Stop stop waiting for signals, a blocked Close returns without calling its handles
+	Stop()
 }
 
 type hook struct {
-	ctx chan os.Signal
+	ctx      chan os.Signal
+	done     chan struct{}
+	stopOnce sync.Once
 }
 
 // NewHook create a Hook instance
 func NewHook() Hook {
 	hook := &hook{
-		ctx: make(chan os.Signal, 1),
+		ctx:  make(chan os.Signal, 1),
+		done: make(chan struct{}),
 	}
 
 	return hook.WithSignals(syscall.SIGINT, syscall.SIGTERM)
@@ -43,6 +50,8 @@ func (h *hook) WithSignals(signals ...syscall.Signal) Hook {
 func (h *hook) Close(funcs ...func()) {
 	select {
 	case <-h.ctx:
+	case <-h.done:
+		return
 	}
 	signal.Stop(h.ctx)
 
@@ -50,3 +59,11 @@ func (h *hook) Close(funcs ...func()) {
 		f()
 	}
 }
+
+// Stop stop waiting for signals
+func (h *hook) Stop() {
+	h.stopOnce.Do(func() {
+		signal.Stop(h.ctx)
+		close(h.done)
+	})
+}
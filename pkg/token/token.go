@@ -30,6 +30,9 @@ type Token interface {
 	// UrlSign URL 签名
 	// 防参数篡改，防重放攻击
 	UrlSign(timestamp int64, path string, method string, params url.Values) (tokenString string, err error)
+
+	// UrlSignHmac URL 签名，签名字符串同 UrlSign，使用 HMAC-SHA256
+	UrlSignHmac(timestamp int64, path string, method string, params url.Values) (tokenString string, err error)
 }
 
 type token struct {
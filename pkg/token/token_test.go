@@ -1,6 +1,10 @@
 package token
 
 import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/url"
 	"testing"
 	"time"
@@ -40,6 +44,36 @@ func TestUrlSign(t *testing.T) {
 	t.Log(tokenString)
 }
 
+func TestUrlSignHmac(t *testing.T) {
+	params := url.Values{}
+	params.Add("b", "b1")
+	params.Add("a", "a1")
+
+	// md5 签名保持不变
+	md5Sign, err := New(secret).UrlSign(1650000000, "/echo", "POST", params)
+	if err != nil {
+		t.Fatal("sign error", err)
+	}
+	sum := md5.Sum([]byte("/echoposta=a1&b=b11650000000" + secret))
+	if md5Sign != hex.EncodeToString(sum[:]) {
+		t.Error("unexpected md5 sign", md5Sign)
+	}
+
+	hmacSign, err := New(secret).UrlSignHmac(1650000000, "/echo", "PATCH", params)
+	if err != nil {
+		t.Fatal("sign error", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("/echopatcha=a1&b=b11650000000"))
+	if hmacSign != hex.EncodeToString(mac.Sum(nil)) {
+		t.Error("unexpected hmac sign", hmacSign)
+	}
+
+	if _, err = New(secret).UrlSignHmac(1650000000, "/echo", "path", params); err == nil {
+		t.Error("invalid method should be rejected")
+	}
+}
+
 func BenchmarkJwtSignAndParse(b *testing.B) {
 	b.ResetTimer()
 	token := New(secret)
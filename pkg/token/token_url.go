@@ -1,7 +1,9 @@
 package token
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -9,31 +11,27 @@ import (
 	"strings"
 )
 
+// 合法的 Methods
+var signMethods = map[string]bool{
+	"get":     true,
+	"post":    true,
+	"put":     true,
+	"patch":   true,
+	"delete":  true,
+	"head":    true,
+	"options": true,
+}
+
 // UrlSign
 // path 请求的路径 (不附带 querystring)
 func (t *token) UrlSign(timestamp int64, path string, method string, params url.Values) (tokenString string, err error) {
-	// 合法的 Methods
-	methods := map[string]bool{
-		"get":     true,
-		"post":    true,
-		"put":     true,
-		"path":    true,
-		"delete":  true,
-		"head":    true,
-		"options": true,
-	}
-
-	methodName := strings.ToLower(method)
-	if !methods[methodName] {
-		err = errors.New("method param error")
+	signStr, err := urlSignString(timestamp, path, method, params)
+	if err != nil {
 		return
 	}
 
-	// Encode() 方法中自带 sorted by key
-	sortParamsEncode := params.Encode()
-
-	// 加密字符串规则 path + method + sortParamsEncode + secret
-	encryptStr := fmt.Sprintf("%s%s%s%d%s", path, methodName, sortParamsEncode, timestamp, t.secret)
+	// 加密字符串规则 path + method + sortParamsEncode + timestamp + secret
+	encryptStr := signStr + t.secret
 
 	// 对加密字符串进行 md5
 	s := md5.New()
@@ -43,3 +41,29 @@ func (t *token) UrlSign(timestamp int64, path string, method string, params url.
 	tokenString = md5Str
 	return
 }
+
+// UrlSignHmac
+// path 请求的路径 (不附带 querystring)，secret 作为 HMAC 的 key，不参与拼接
+func (t *token) UrlSignHmac(timestamp int64, path string, method string, params url.Values) (tokenString string, err error) {
+	signStr, err := urlSignString(timestamp, path, method, params)
+	if err != nil {
+		return
+	}
+
+	mac := hmac.New(sha256.New, []byte(t.secret))
+	mac.Write([]byte(signStr))
+	tokenString = hex.EncodeToString(mac.Sum(nil))
+	return
+}
+
+// urlSignString 签名字符串 path + method + sortParamsEncode + timestamp
+func urlSignString(timestamp int64, path string, method string, params url.Values) (string, error) {
+	methodName := strings.ToLower(method)
+	if !signMethods[methodName] {
+		return "", errors.New("method param error")
+	}
+
+	// Encode() 方法中自带 sorted by key
+	sortParamsEncode := params.Encode()
+	return fmt.Sprintf("%s%s%s%d", path, methodName, sortParamsEncode, timestamp), nil
+}